@@ -0,0 +1,188 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// ArrayItemsError is returned when items of a JSON array request body fail
+// validation. When Options.MaxArrayItemErrors is reached, validation stops
+// early, so only the first invalid items are reported.
+type ArrayItemsError struct {
+	// Indices of the invalid items, in ascending order
+	Indices []int
+	// Errors holds the validation error for each of the Indices
+	Errors []error
+
+	// collapse merges runs of items with the same error in the message, see
+	// Options.CollapseRepeatedErrors
+	collapse bool
+}
+
+func (e *ArrayItemsError) Error() string {
+	messages := make([]string, 0, len(e.Indices))
+	for i := 0; i < len(e.Indices); i++ {
+		message := e.Errors[i].Error()
+		run := 1
+		for e.collapse && i+run < len(e.Indices) && repeatedErrorKey(e.Errors[i+run]) == repeatedErrorKey(e.Errors[i]) {
+			run++
+		}
+		if run == 1 {
+			messages = append(messages, fmt.Sprintf("item %d: %s", e.Indices[i], message))
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("items %d-%d: %s (x%d)", e.Indices[i], e.Indices[i+run-1], message, run))
+		i += run - 1
+	}
+	return fmt.Sprintf("request body has invalid items at indices %v: %s", e.Indices, strings.Join(messages, "; "))
+}
+
+// arrayBodySchema returns the schema of the request body when it's a JSON
+// array of items which can be validated one at a time, or nil otherwise.
+func arrayBodySchema(req *http.Request, route *routers.Route) *openapi3.Schema {
+	if route.Operation.RequestBody == nil || route.Operation.RequestBody.Value == nil {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return nil
+	}
+	content := route.Operation.RequestBody.Value.Content.Get(mediaType)
+	if content == nil || content.Schema == nil || content.Schema.Value == nil {
+		return nil
+	}
+	schema := content.Schema.Value
+	if !schema.Type.Is(openapi3.TypeArray) || schema.Items == nil || schema.Items.Value == nil {
+		return nil
+	}
+	return schema
+}
+
+// validateArrayItems validates a JSON array request body against schema,
+// one item at a time, giving up after maxErrors invalid items. The body is
+// left readable for the handler.
+func validateArrayItems(req *http.Request, schema *openapi3.Schema, required bool, maxErrors int) error {
+	var data []byte
+	if req.Body != nil {
+		var err error
+		data, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrRequestBodyTooLarge, maxBytesErr.Limit)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	if len(data) == 0 {
+		if required {
+			return fmt.Errorf("request body has an error: %w", openapi3filter.ErrInvalidRequired)
+		}
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("request body has an error: failed to decode request body: %w", err)
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		// Not an array, such as null or an object, so there are no items to
+		// report on: validate the body as a whole
+		if err := schema.VisitJSON(value, openapi3.VisitAsRequest()); err != nil {
+			return fmt.Errorf("request body has an error: doesn't match schema: %w", err)
+		}
+		return nil
+	}
+
+	itemsErr := &ArrayItemsError{}
+	for i, item := range items {
+		if err := schema.Items.Value.VisitJSON(item, openapi3.VisitAsRequest()); err != nil {
+			itemsErr.Indices = append(itemsErr.Indices, i)
+			itemsErr.Errors = append(itemsErr.Errors, err)
+			if len(itemsErr.Indices) == maxErrors {
+				break
+			}
+		}
+	}
+	if len(itemsErr.Indices) > 0 {
+		return itemsErr
+	}
+
+	// The items are valid, check the constraints on the array itself
+	arraySchema := *schema
+	arraySchema.Items = nil
+	if err := arraySchema.VisitJSON(items, openapi3.VisitAsRequest()); err != nil {
+		return fmt.Errorf("request body has an error: doesn't match schema: %w", err)
+	}
+	return nil
+}
+
+// checkArrayMaxItems reads a JSON array request body, whose schema sets
+// maxItems, one item at a time, and returns an error as soon as it has too
+// many items. The rest of the body is left unread. Bodies which aren't
+// arrays, or are malformed, are left for the full validation to report. The
+// body is left readable for validation and the handler.
+func checkArrayMaxItems(req *http.Request, route *routers.Route) error {
+	schema := arrayBodySchema(req, route)
+	if schema == nil || schema.MaxItems == nil || req.Body == nil {
+		return nil
+	}
+
+	var read bytes.Buffer
+	body := req.Body
+	defer func() {
+		req.Body = readCloser{io.MultiReader(&read, body), body}
+	}()
+
+	decoder := json.NewDecoder(io.TeeReader(body, &read))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil
+	}
+	var count uint64
+	for decoder.More() {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil
+		}
+		if count++; count > *schema.MaxItems {
+			return &openapi3filter.RequestError{
+				RequestBody: route.Operation.RequestBody.Value,
+				Reason:      fmt.Sprintf("maximum number of items is %d", *schema.MaxItems),
+			}
+		}
+	}
+	return nil
+}
+
+// readCloser reads from a reader and closes a closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
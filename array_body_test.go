@@ -0,0 +1,297 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOapiRequestValidatorMaxArrayItemErrors(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		MaxArrayItemErrors: 3,
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	var received []map[string]interface{}
+	called := false
+	g.POST("/bulk_resource", func(c *gin.Context) {
+		called = true
+		require.NoError(t, c.ShouldBindJSON(&received))
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's send a valid array, it should pass and be readable by the handler
+	{
+		body := []map[string]interface{}{
+			{"id": 1, "name": "a"},
+			{"id": 2, "name": "b"},
+		}
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", body)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		assert.Len(t, received, 2)
+		called = false
+	}
+
+	// Send an array with invalid items at 1, 3, 4 and 6. Only the first
+	// three should be reported.
+	{
+		body := []map[string]interface{}{
+			{"id": 1, "name": "a"},
+			{"id": 0, "name": "b"},
+			{"id": 2, "name": "c"},
+			{"id": 3},
+			{"id": "four", "name": "e"},
+			{"id": 5, "name": "f"},
+			{"id": -6, "name": "g"},
+		}
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid items at indices [1 3 4]")
+		assert.NotContains(t, rec.Body.String(), "item 6")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send something which isn't an array, it's validated as a whole
+	{
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", map[string]interface{}{"id": 1, "name": "a"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "value must be an array")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// A null body is rejected just like without the option
+	{
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/bulk_resource", strings.NewReader("null"))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Value is not nullable")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorCollapseRepeatedErrors(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		MaxArrayItemErrors:     10,
+		CollapseRepeatedErrors: true,
+	}))
+	g.POST("/bulk_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	body := []map[string]interface{}{
+		{"id": 0, "name": "a"},
+		{"id": 0, "name": "b"},
+		{"id": 0, "name": "c"},
+		{"id": 3},
+		{"id": 0, "name": "e"},
+	}
+	rec := doPost(t, g, "http://deepmap.ai/bulk_resource", body)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var response map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	message := response["error"]
+	assert.Contains(t, message, "invalid items at indices [0 1 2 3 4]")
+	assert.Contains(t, message, "items 0-2: ")
+	assert.Contains(t, message, " (x3); item 3: ")
+	assert.Contains(t, message, "; item 4: ")
+	// Items 0 to 2 are reported once, item 4 separately
+	assert.Equal(t, 2, strings.Count(message, "number must be at least 1"))
+}
+
+func TestOapiRequestValidatorStreamArrayMaxItems(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		StreamArrayMaxItems: true,
+	}))
+	var received []map[string]interface{}
+	g.POST("/bulk_resource", func(c *gin.Context) {
+		received = nil
+		require.NoError(t, c.ShouldBindJSON(&received))
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// streamItems posts an array of n items, written one at a time, and
+	// returns how many were written before the request was answered.
+	streamItems := func(n int) (*httptest.ResponseRecorder, int) {
+		pr, pw := io.Pipe()
+		written := make(chan int, 1)
+		go func() {
+			i := 0
+			_, err := pw.Write([]byte("["))
+			for ; err == nil && i < n; i++ {
+				item := `{"name": "item"}`
+				if i > 0 {
+					item = "," + item
+				}
+				_, err = pw.Write([]byte(item))
+			}
+			if err == nil {
+				_, err = pw.Write([]byte("]"))
+			}
+			pw.CloseWithError(err)
+			written <- i
+		}()
+
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/bulk_resource", pr)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		pr.Close()
+		return rec, <-written
+	}
+
+	// An array within maxItems is read in full and reaches the handler
+	{
+		rec, written := streamItems(100)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, 100, written)
+		assert.Len(t, received, 100)
+	}
+
+	// A longer one is rejected once maxItems, 10000, is exceeded, long
+	// before the client is done sending it
+	{
+		received = nil
+		rec, written := streamItems(1000000)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error: maximum number of items is 10000")
+		assert.Less(t, written, 20000)
+		assert.Nil(t, received, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorCollapseRepeatedErrorsMultiError(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	body := []map[string]interface{}{
+		{"id": 0, "name": "a"},
+		{"id": -1, "name": "b"},
+		{"id": 0, "name": "c"},
+		{"id": 3},
+		{"id": 0, "name": "e"},
+	}
+	post := func(collapse bool) string {
+		g := gin.New()
+		g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+			CollectAllErrors:       true,
+			CollapseRepeatedErrors: collapse,
+		}))
+		g.POST("/bulk_resource", func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", body)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response["error"]
+	}
+
+	// Every item's error is reported without the option
+	message := post(false)
+	assert.Equal(t, 4, strings.Count(message, "number must be at least 1"))
+
+	// With it, the errors of items 0 to 2 are merged, though their pointers
+	// and values differ, while item 4's is kept apart by item 3's
+	message = post(true)
+	assert.Equal(t, 2, strings.Count(message, "number must be at least 1"))
+	assert.Contains(t, message, `Error at "/0/id": number must be at least 1`)
+	assert.NotContains(t, message, `"/1/id"`)
+	assert.Contains(t, message, "(x3)")
+	assert.Contains(t, message, `Error at "/3/name": property "name" is missing`)
+	assert.Contains(t, message, `Error at "/4/id": number must be at least 1`)
+}
+
+func TestCollapseMultiError(t *testing.T) {
+	a, b := errors.New("a"), errors.New("b")
+	collapsed := collapseMultiError(openapi3.MultiError{a, a, b, a})
+	assert.Equal(t, "a (x2) | b | a", collapsed.Error())
+	assert.ErrorIs(t, collapsed[0], a)
+}
+
+func BenchmarkArrayBodyValidation(b *testing.B) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(b, err, "Error initializing swagger")
+
+	items := make([]map[string]interface{}, 5000)
+	for i := range items {
+		// Every item is invalid
+		items[i] = map[string]interface{}{"id": 0, "name": "item"}
+	}
+	body, err := json.Marshal(items)
+	require.NoError(b, err)
+
+	for _, bm := range []struct {
+		name    string
+		options *Options
+	}{
+		{"MultiError", &Options{
+			Options:               openapi3filter.Options{MultiError: true},
+			SilenceServersWarning: true,
+		}},
+		{"MaxArrayItemErrors", &Options{
+			Options:               openapi3filter.Options{MultiError: true},
+			SilenceServersWarning: true,
+			MaxArrayItemErrors:    10,
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			g := gin.New()
+			g.Use(OapiRequestValidatorWithOptions(swagger, bm.options))
+			g.POST("/bulk_resource", func(c *gin.Context) {})
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r, _ := http.NewRequest(http.MethodPost, "http://deepmap.ai/bulk_resource", io.NopCloser(bytes.NewReader(body)))
+				r.Header.Set("content-type", "application/json")
+				rec := httptest.NewRecorder()
+				g.ServeHTTP(rec, r)
+				if rec.Code != http.StatusBadRequest {
+					b.Fatalf("unexpected status %d", rec.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// ErrNoGinContext is returned by the authenticators when they're called
+	// outside of the middleware, so that no gin context is available.
+	ErrNoGinContext = errors.New("gin context not found")
+	// ErrMissingAPIKey is returned when the API key header is absent
+	ErrMissingAPIKey = errors.New("missing API key")
+	// ErrMissingBearerToken is returned when the Authorization header is
+	// absent or doesn't carry a bearer token
+	ErrMissingBearerToken = errors.New("missing bearer token")
+	// ErrUnsupportedSecurityScheme is returned by the authenticators for
+	// security schemes they don't handle, such as a bearer scheme given to an
+	// API key authenticator
+	ErrUnsupportedSecurityScheme = errors.New("unsupported security scheme")
+)
+
+// NewAPIKeyAuthenticator returns an AuthenticationFunc which reads an API key
+// from the given request header and passes it to validate. It only handles
+// apiKey security schemes sent in that header, and fails others with
+// ErrUnsupportedSecurityScheme.
+func NewAPIKeyAuthenticator(header string, validate func(c *gin.Context, key string) error) openapi3filter.AuthenticationFunc {
+	return func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+		securityScheme := input.SecurityScheme
+		if securityScheme == nil || securityScheme.Type != "apiKey" || securityScheme.In != "header" ||
+			!strings.EqualFold(securityScheme.Name, header) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedSecurityScheme, input.SecuritySchemeName)
+		}
+		c := GetGinContext(ctx)
+		if c == nil {
+			return ErrNoGinContext
+		}
+		key := c.GetHeader(header)
+		if key == "" {
+			return ErrMissingAPIKey
+		}
+		return validate(c, key)
+	}
+}
+
+// NewBearerAuthenticator returns an AuthenticationFunc which reads a bearer
+// token from the Authorization header and passes it to validate. It only
+// handles http security schemes using bearer, and fails others with
+// ErrUnsupportedSecurityScheme.
+func NewBearerAuthenticator(validate func(c *gin.Context, token string) error) openapi3filter.AuthenticationFunc {
+	return func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+		securityScheme := input.SecurityScheme
+		if securityScheme == nil || securityScheme.Type != "http" || !strings.EqualFold(securityScheme.Scheme, "bearer") {
+			return fmt.Errorf("%w: %s", ErrUnsupportedSecurityScheme, input.SecuritySchemeName)
+		}
+		c := GetGinContext(ctx)
+		if c == nil {
+			return ErrNoGinContext
+		}
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return ErrMissingBearerToken
+		}
+		return validate(c, token)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKeyAuthenticator(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		Options: openapi3filter.Options{
+			AuthenticationFunc: NewAPIKeyAuthenticator("X-Api-Key", func(c *gin.Context, key string) error {
+				assert.NotNil(t, c)
+				if key != "secret" {
+					return errors.New("invalid API key")
+				}
+				return nil
+			}),
+		},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.GET("/api_key_protected_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Send the correct key, it should pass
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/api_key_protected_resource", map[string]string{
+			"X-Api-Key": "secret",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Send the wrong key
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/api_key_protected_resource", map[string]string{
+			"X-Api-Key": "guess",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid API key")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send no key at all
+	{
+		rec := doGet(t, g, "http://deepmap.ai/api_key_protected_resource")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrMissingAPIKey.Error())
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Other schemes aren't checked against the API key header
+	{
+		g.GET("/protected_resource", func(c *gin.Context) {
+			called = true
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/protected_resource", map[string]string{
+			"X-Api-Key": "secret",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrUnsupportedSecurityScheme.Error()+": BearerAuth")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestNewBearerAuthenticator(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		Options: openapi3filter.Options{
+			AuthenticationFunc: NewBearerAuthenticator(func(c *gin.Context, token string) error {
+				assert.NotNil(t, c)
+				if token != "good-token" {
+					return errors.New("invalid token")
+				}
+				return nil
+			}),
+		},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.GET("/protected_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Send the correct token, it should pass
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/protected_resource", map[string]string{
+			"Authorization": "Bearer good-token",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Send the wrong token
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/protected_resource", map[string]string{
+			"Authorization": "Bearer bad-token",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid token")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send credentials using another scheme
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/protected_resource", map[string]string{
+			"Authorization": "Basic dXNlcjpwYXNz",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrMissingBearerToken.Error())
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// API key schemes aren't checked against the Authorization header
+	{
+		g.GET("/api_key_protected_resource", func(c *gin.Context) {
+			called = true
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/api_key_protected_resource", map[string]string{
+			"Authorization": "Bearer good-token",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrUnsupportedSecurityScheme.Error()+": ApiKeyAuth")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
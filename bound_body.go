@@ -0,0 +1,55 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// ValidateBoundBody validates value, a request body already bound by the
+// handler, for instance with c.ShouldBindJSON, against the application/json
+// request body schema of the operation. It's meant to be combined with
+// openapi3filter.Options.ExcludeRequestBody, so that the body is read once.
+// value is compared through its JSON encoding, so its json tags apply.
+func ValidateBoundBody(swagger *openapi3.T, operationID string, value interface{}) error {
+	operation := findOperation(swagger, operationID)
+	if operation == nil {
+		return fmt.Errorf("operation %q not found", operationID)
+	}
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return fmt.Errorf("operation %q has no request body", operationID)
+	}
+	requestBody := operation.RequestBody.Value
+	mediaType := requestBody.Content.Get("application/json")
+	if mediaType == nil || mediaType.Schema == nil || mediaType.Schema.Value == nil {
+		return fmt.Errorf("operation %q has no application/json request body schema", operationID)
+	}
+
+	normalized, err := normalizeJSON(value)
+	if err != nil {
+		return fmt.Errorf("error encoding request body: %w", err)
+	}
+	if err := mediaType.Schema.Value.VisitJSON(normalized, openapi3.VisitAsRequest()); err != nil {
+		return &openapi3filter.RequestError{
+			RequestBody: requestBody,
+			Reason:      "doesn't match schema",
+			Err:         err,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBoundBody(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	type item struct {
+		ID int `json:"id"`
+	}
+	type nestedResource struct {
+		Items []item `json:"items"`
+	}
+
+	g := gin.New()
+	// The body is left to the handler
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		Options: openapi3filter.Options{ExcludeRequestBody: true},
+	}))
+	g.POST("/nested_resource", func(c *gin.Context) {
+		var body nestedResource
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ValidateBoundBody(swagger, "createNestedResource", body); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doPost(t, g, "http://deepmap.ai/nested_resource", gin.H{"items": []gin.H{{"id": 1}}})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doPost(t, g, "http://deepmap.ai/nested_resource", gin.H{"items": []gin.H{{"id": 0}}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request body has an error: doesn't match schema")
+	assert.Contains(t, rec.Body.String(), "number must be at least 1")
+
+	err = ValidateBoundBody(swagger, "getResource", nestedResource{})
+	assert.Error(t, err, "operations without a body can't be validated")
+
+	// readOnly properties are rejected, as they are by the middleware
+	type account struct {
+		ID   int    `json:"id,omitempty"`
+		Name string `json:"name"`
+	}
+	assert.NoError(t, ValidateBoundBody(swagger, "createAccountResource", account{Name: "Wilma"}))
+	err = ValidateBoundBody(swagger, "createAccountResource", account{ID: 7, Name: "Wilma"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "readOnly property \"id\" in request")
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// CatchAllExtension marks a path parameter whose value may contain slashes,
+// such as path in /files/{path}, which then matches /files/a/b/c with path
+// set to `a/b/c`. Path parameters otherwise match a single segment.
+const CatchAllExtension = "x-catch-all"
+
+// catchAllRouter routes requests with a router built from the spec as is,
+// and only when it finds no route, falls back on one built from the spec's
+// catch-all paths, in which catch-all parameters match any number of
+// segments. More specific paths, such as /files/{id}/meta next to
+// /files/{path}, thus keep their requests. Fallback routes are reported in
+// terms of the original spec.
+type catchAllRouter struct {
+	routers.Router
+	fallback routers.Router
+	spec     *openapi3.T
+	// paths maps the rewritten path templates to the spec's
+	paths map[string]string
+}
+
+func (r *catchAllRouter) FindRoute(req *http.Request) (*routers.Route, map[string]string, error) {
+	route, pathParams, err := r.Router.FindRoute(req)
+	var routeErr *routers.RouteError
+	if err == nil || !errors.As(err, &routeErr) {
+		return route, pathParams, err
+	}
+	fallbackRoute, fallbackParams, fallbackErr := r.fallback.FindRoute(req)
+	if fallbackErr != nil {
+		return route, pathParams, err
+	}
+	fallbackRoute.Path = r.paths[fallbackRoute.Path]
+	fallbackRoute.Spec = r.spec
+	return fallbackRoute, fallbackParams, nil
+}
+
+// newRouter creates the router used to match requests to operations,
+// honouring CatchAllExtension.
+func newRouter(swagger *openapi3.T) (routers.Router, error) {
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil || swagger.Paths == nil {
+		return router, err
+	}
+
+	paths := make(map[string]string)
+	rewritten := openapi3.NewPaths()
+	for path, pathItem := range swagger.Paths.Map() {
+		routerPath := path
+		for _, name := range catchAllParams(pathItem) {
+			routerPath = strings.ReplaceAll(routerPath, "{"+name+"}", "{"+name+":.+}")
+		}
+		if routerPath != path {
+			paths[routerPath] = path
+			rewritten.Set(routerPath, pathItem)
+		}
+	}
+	if len(paths) == 0 {
+		return router, nil
+	}
+
+	spec := *swagger
+	spec.Paths = rewritten
+	fallback, err := gorillamux.NewRouter(&spec)
+	if err != nil {
+		return nil, err
+	}
+	return &catchAllRouter{Router: router, fallback: fallback, spec: swagger, paths: paths}, nil
+}
+
+// catchAllParams returns the names of the path parameters of a path item
+// or its operations which are marked with CatchAllExtension.
+func catchAllParams(pathItem *openapi3.PathItem) []string {
+	var names []string
+	add := func(parameters openapi3.Parameters) {
+		for _, parameterRef := range parameters {
+			parameter := parameterRef.Value
+			if parameter == nil || parameter.In != openapi3.ParameterInPath {
+				continue
+			}
+			if catchAll, _ := parameter.Extensions[CatchAllExtension].(bool); catchAll {
+				names = append(names, parameter.Name)
+			}
+		}
+	}
+	add(pathItem.Parameters)
+	for _, operation := range pathItem.Operations() {
+		add(operation.Parameters)
+	}
+	return names
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// repeatedError is a run of equivalent errors, collapsed into the first one.
+type repeatedError struct {
+	err   error
+	count int
+}
+
+func (e *repeatedError) Error() string {
+	return fmt.Sprintf("%s (x%d)", e.err, e.count)
+}
+
+func (e *repeatedError) Unwrap() error {
+	return e.err
+}
+
+// collapseMultiError merges consecutive equivalent errors of me, see
+// Options.CollapseRepeatedErrors. The schema errors of a request body, which
+// openapi3filter wraps in a single RequestError, are merged too.
+func collapseMultiError(me openapi3.MultiError) openapi3.MultiError {
+	collapsed := make(openapi3.MultiError, 0, len(me))
+	for i := 0; i < len(me); i++ {
+		key := repeatedErrorKey(me[i])
+		run := 1
+		for i+run < len(me) && repeatedErrorKey(me[i+run]) == key {
+			run++
+		}
+		err := collapseNested(me[i])
+		if run == 1 {
+			collapsed = append(collapsed, err)
+			continue
+		}
+		collapsed = append(collapsed, &repeatedError{err: err, count: run})
+		i += run - 1
+	}
+	return collapsed
+}
+
+// collapseNested collapses the MultiError wrapped by a RequestError, if any
+func collapseNested(err error) error {
+	requestErr, ok := err.(*openapi3filter.RequestError)
+	if !ok {
+		return err
+	}
+	me, ok := requestErr.Err.(openapi3.MultiError)
+	if !ok {
+		return err
+	}
+	collapsedErr := *requestErr
+	collapsedErr.Err = collapseMultiError(me)
+	return &collapsedErr
+}
+
+// repeatedErrorKey returns what errors are compared on to be merged. Schema
+// errors about different items of an array, such as at /0/id and /1/id,
+// embed their index and value in their message, so they're compared on their
+// reason, the failed schema keyword and their path without array indices.
+func repeatedErrorKey(err error) string {
+	schemaErr, ok := err.(*openapi3.SchemaError)
+	if !ok {
+		return err.Error()
+	}
+	pointer := schemaErr.JSONPointer()
+	tokens := make([]string, len(pointer))
+	for i, token := range pointer {
+		if _, err := strconv.Atoi(token); err == nil {
+			token = "*"
+		}
+		tokens[i] = token
+	}
+	return schemaErr.SchemaField + "\x00" + schemaErr.Reason + "\x00" + strings.Join(tokens, "/")
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+)
+
+// CompatibilityWarning describes a feature of a spec which the middleware
+// may not handle the way its author expects.
+type CompatibilityWarning struct {
+	// Location of the feature in the spec, such as `paths./pets.get`
+	Location string
+	// Message explains the problem
+	Message string
+}
+
+func (w CompatibilityWarning) String() string {
+	return w.Location + ": " + w.Message
+}
+
+// supportedParameterStyles lists the parameter styles which openapi3filter
+// can decode, by parameter location.
+var supportedParameterStyles = map[string][]string{
+	openapi3.ParameterInPath:   {openapi3.SerializationSimple, openapi3.SerializationLabel, openapi3.SerializationMatrix},
+	openapi3.ParameterInQuery:  {openapi3.SerializationForm, openapi3.SerializationSpaceDelimited, openapi3.SerializationPipeDelimited, openapi3.SerializationDeepObject},
+	openapi3.ParameterInHeader: {openapi3.SerializationSimple},
+	openapi3.ParameterInCookie: {openapi3.SerializationForm},
+}
+
+// CheckSpecCompatibility lints a spec for features this middleware
+// mishandles or handles in a surprising way, such as servers causing Host
+// validation, parameter styles which can't be decoded and request bodies of
+// content types without a registered body decoder. Warnings are returned
+// sorted by location.
+func CheckSpecCompatibility(swagger *openapi3.T) []CompatibilityWarning {
+	var warnings []CompatibilityWarning
+	warn := func(location, format string, args ...interface{}) {
+		warnings = append(warnings, CompatibilityWarning{Location: location, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(swagger.Servers) > 1 {
+		warn("servers", "%d servers are declared, requests must match the host and base path of one of them", len(swagger.Servers))
+	} else if len(swagger.Servers) == 1 {
+		warn("servers", "requests must match the host and base path of server %s, see https://github.com/deepmap/oapi-codegen/issues/882", swagger.Servers[0].URL)
+	}
+
+	if swagger.Paths == nil {
+		return warnings
+	}
+	for path, pathItem := range swagger.Paths.Map() {
+		pathLocation := "paths." + path
+		if len(pathItem.Servers) > 0 {
+			warn(pathLocation+".servers", "path level servers replace the spec's servers for this path")
+		}
+		for _, parameterRef := range pathItem.Parameters {
+			checkParameterStyle(parameterRef, pathLocation, warn)
+		}
+		for method, operation := range pathItem.Operations() {
+			operationLocation := pathLocation + "." + strings.ToLower(method)
+			for _, parameterRef := range operation.Parameters {
+				checkParameterStyle(parameterRef, operationLocation, warn)
+			}
+			if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+				continue
+			}
+			for contentType := range operation.RequestBody.Value.Content {
+				mediaType, _, err := mime.ParseMediaType(contentType)
+				if err != nil {
+					mediaType = contentType
+				}
+				if strings.Contains(mediaType, "*") {
+					continue
+				}
+				if openapi3filter.RegisteredBodyDecoder(mediaType) == nil {
+					warn(operationLocation+".requestBody", "no body decoder is registered for %s, such bodies will be rejected", contentType)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Location < warnings[j].Location
+	})
+	return warnings
+}
+
+func checkParameterStyle(parameterRef *openapi3.ParameterRef, location string, warn func(location, format string, args ...interface{})) {
+	if parameterRef == nil || parameterRef.Value == nil {
+		return
+	}
+	parameter := parameterRef.Value
+	if parameter.Content != nil {
+		return
+	}
+	sm, err := parameter.SerializationMethod()
+	if err != nil {
+		warn(location+".parameters."+parameter.Name, "%s", err)
+		return
+	}
+	for _, style := range supportedParameterStyles[parameter.In] {
+		if sm.Style == style {
+			return
+		}
+	}
+	warn(location+".parameters."+parameter.Name, "style %q is not supported for %s parameters", sm.Style, parameter.In)
+}
+
+// ErrUncoveredSecuritySchemes is returned by CheckAuthCoverage when requests
+// to some operations can't be authenticated.
+var ErrUncoveredSecuritySchemes = errors.New("security schemes without authentication coverage")
+
+// CheckAuthCoverage checks that every security scheme required by an
+// operation of the spec can be authenticated with options: the scheme must
+// be defined, and handled by the AuthenticationFunc. authenticatedSchemes
+// lists the schemes the AuthenticationFunc handles; when empty, it's assumed
+// to handle every scheme. It returns an error wrapping
+// ErrUncoveredSecuritySchemes and listing the uncovered schemes otherwise.
+func CheckAuthCoverage(swagger *openapi3.T, options *Options, authenticatedSchemes []string) error {
+	if options != nil && options.SkipSecurityValidation {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	addRequirements := func(requirements openapi3.SecurityRequirements) {
+		for _, requirement := range requirements {
+			for name := range requirement {
+				used[name] = true
+			}
+		}
+	}
+	addRequirements(swagger.Security)
+	if swagger.Paths != nil {
+		for _, pathItem := range swagger.Paths.Map() {
+			for _, operation := range pathItem.Operations() {
+				if operation.Security != nil {
+					addRequirements(*operation.Security)
+				}
+			}
+		}
+	}
+
+	var authenticated map[string]bool
+	if len(authenticatedSchemes) > 0 {
+		authenticated = make(map[string]bool, len(authenticatedSchemes))
+		for _, name := range authenticatedSchemes {
+			authenticated[name] = true
+		}
+	}
+	hasAuthenticationFunc := options != nil && options.Options.AuthenticationFunc != nil
+
+	var uncovered []string
+	for name := range used {
+		var reason string
+		switch {
+		case swagger.Components == nil || swagger.Components.SecuritySchemes[name] == nil:
+			reason = "not defined"
+		case !hasAuthenticationFunc:
+			reason = "no AuthenticationFunc"
+		case authenticated != nil && !authenticated[name]:
+			reason = "not handled by the AuthenticationFunc"
+		default:
+			continue
+		}
+		uncovered = append(uncovered, fmt.Sprintf("%s (%s)", name, reason))
+	}
+	if len(uncovered) == 0 {
+		return nil
+	}
+	sort.Strings(uncovered)
+	return fmt.Errorf("%w: %s", ErrUncoveredSecuritySchemes, strings.Join(uncovered, ", "))
+}
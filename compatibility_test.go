@@ -0,0 +1,155 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSpecCompatibility(t *testing.T) {
+	spec := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+servers:
+  - url: https://api.example.com/v1
+  - url: https://staging.example.com/v1
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: tags
+          in: header
+          style: form
+          schema:
+            type: array
+            items:
+              type: string
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      responses:
+        '200':
+          description: ok
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+          application/vnd.custom:
+            schema:
+              type: string
+      responses:
+        '204':
+          description: no content
+`)
+	swagger, err := openapi3.NewLoader().LoadFromData(spec)
+	require.NoError(t, err, "Error initializing swagger")
+
+	warnings := CheckSpecCompatibility(swagger)
+	locations := make([]string, len(warnings))
+	for i, w := range warnings {
+		locations[i] = w.Location
+	}
+	assert.Equal(t, []string{
+		"paths./pets.get.parameters.tags",
+		"paths./pets.post.requestBody",
+		"servers",
+	}, locations)
+	assert.Contains(t, warnings[0].Message, `style "form" is not supported for header parameters`)
+	assert.Contains(t, warnings[1].Message, "application/vnd.custom")
+	assert.Contains(t, warnings[2].Message, "2 servers are declared")
+}
+
+func TestCheckSpecCompatibilityNoWarnings(t *testing.T) {
+	spec := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      responses:
+        '200':
+          description: ok
+`)
+	swagger, err := openapi3.NewLoader().LoadFromData(spec)
+	require.NoError(t, err, "Error initializing swagger")
+
+	assert.Empty(t, CheckSpecCompatibility(swagger))
+}
+
+func TestCheckAuthCoverage(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	authenticate := func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+		return nil
+	}
+
+	// Without an AuthenticationFunc, no scheme is covered
+	err = CheckAuthCoverage(swagger, nil, nil)
+	require.ErrorIs(t, err, ErrUncoveredSecuritySchemes)
+	assert.Contains(t, err.Error(), "ApiKeyAuth (no AuthenticationFunc), BearerAuth (no AuthenticationFunc), CookieAuth (no AuthenticationFunc)")
+
+	// An AuthenticationFunc covers every scheme by default
+	options := &Options{Options: openapi3filter.Options{AuthenticationFunc: authenticate}}
+	assert.NoError(t, CheckAuthCoverage(swagger, options, nil))
+
+	// unless it declares which ones it handles
+	err = CheckAuthCoverage(swagger, options, []string{"BearerAuth", "ApiKeyAuth"})
+	require.ErrorIs(t, err, ErrUncoveredSecuritySchemes)
+	assert.Equal(t, "security schemes without authentication coverage: CookieAuth (not handled by the AuthenticationFunc)", err.Error())
+
+	// Skipping security validation covers everything
+	assert.NoError(t, CheckAuthCoverage(swagger, &Options{SkipSecurityValidation: true}, nil))
+}
+
+func TestCheckAuthCoverageUndefinedScheme(t *testing.T) {
+	spec := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /pets:
+    get:
+      security:
+        - OAuth: []
+      responses:
+        '200':
+          description: ok
+`)
+	swagger, err := openapi3.NewLoader().LoadFromData(spec)
+	require.NoError(t, err, "Error initializing swagger")
+
+	options := &Options{Options: openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}}
+	err = CheckAuthCoverage(swagger, options, nil)
+	require.ErrorIs(t, err, ErrUncoveredSecuritySchemes)
+	assert.Contains(t, err.Error(), "OAuth (not defined)")
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// validateNoDeprecatedParams returns an error if req sets a query, header or
+// cookie parameter of the route which is marked deprecated.
+func validateNoDeprecatedParams(req *http.Request, route *routers.Route) error {
+	query := req.URL.Query()
+	for _, parameters := range []openapi3.Parameters{route.PathItem.Parameters, route.Operation.Parameters} {
+		for _, parameterRef := range parameters {
+			parameter := parameterRef.Value
+			if parameter == nil || !parameter.Deprecated {
+				continue
+			}
+			var present bool
+			switch parameter.In {
+			case openapi3.ParameterInQuery:
+				_, present = query[parameter.Name]
+			case openapi3.ParameterInHeader:
+				_, present = req.Header[http.CanonicalHeaderKey(parameter.Name)]
+			case openapi3.ParameterInCookie:
+				_, err := req.Cookie(parameter.Name)
+				present = err == nil
+			}
+			if present {
+				return fmt.Errorf("parameter %q in %s is deprecated", parameter.Name, parameter.In)
+			}
+		}
+	}
+	return nil
+}
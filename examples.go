@@ -0,0 +1,170 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ErrExampleMismatch is returned by AssertResponseMatchesExample when the
+// body doesn't equal any of the declared examples.
+var ErrExampleMismatch = errors.New("response does not match any declared example")
+
+// AssertResponseMatchesExample is a conformance testing aid. It checks that
+// body, a JSON response returned with the given status by the operation,
+// equals an example declared for that response in the spec. It returns nil
+// on a match.
+func AssertResponseMatchesExample(swagger *openapi3.T, operationID string, status int, body []byte) error {
+	return matchExamples(swagger, operationID, status, body, reflect.DeepEqual)
+}
+
+// AssertResponseMatchesExampleStructure is like AssertResponseMatchesExample,
+// for responses which declare examples but no schema. Rather than being
+// equal to an example, body must have the same structure as one: the same
+// object keys, and values of the same JSON types. Items of an array must
+// each have the structure of one of the example's items.
+func AssertResponseMatchesExampleStructure(swagger *openapi3.T, operationID string, status int, body []byte) error {
+	return matchExamples(swagger, operationID, status, body, sameStructure)
+}
+
+// matchExamples compares body to the examples of an operation's response
+// using match, returning nil when one matches.
+func matchExamples(swagger *openapi3.T, operationID string, status int, body []byte, match func(example, actual interface{}) bool) error {
+	operation := findOperation(swagger, operationID)
+	if operation == nil {
+		return fmt.Errorf("operation %q not found", operationID)
+	}
+
+	var responseRef *openapi3.ResponseRef
+	if operation.Responses != nil {
+		if responseRef = operation.Responses.Status(status); responseRef == nil {
+			responseRef = operation.Responses.Default()
+		}
+	}
+	if responseRef == nil || responseRef.Value == nil {
+		return fmt.Errorf("operation %q has no response for status %d", operationID, status)
+	}
+
+	mediaType := responseRef.Value.Content.Get("application/json")
+	if mediaType == nil {
+		return fmt.Errorf("operation %q has no application/json response for status %d", operationID, status)
+	}
+
+	examples := make([]interface{}, 0, 1+len(mediaType.Examples))
+	if mediaType.Example != nil {
+		examples = append(examples, mediaType.Example)
+	}
+	for _, exampleRef := range mediaType.Examples {
+		if exampleRef != nil && exampleRef.Value != nil && exampleRef.Value.Value != nil {
+			examples = append(examples, exampleRef.Value.Value)
+		}
+	}
+	if len(examples) == 0 {
+		return fmt.Errorf("operation %q declares no example for status %d", operationID, status)
+	}
+
+	var actual interface{}
+	if err := json.Unmarshal(body, &actual); err != nil {
+		return fmt.Errorf("error parsing response body: %w", err)
+	}
+	for _, example := range examples {
+		expected, err := normalizeJSON(example)
+		if err != nil {
+			return fmt.Errorf("error reading example: %w", err)
+		}
+		if match(expected, actual) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: operation %q, status %d", ErrExampleMismatch, operationID, status)
+}
+
+// sameStructure reports whether two decoded JSON documents have the same
+// structure, see AssertResponseMatchesExampleStructure.
+func sameStructure(example, actual interface{}) bool {
+	switch e := example.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok || len(a) != len(e) {
+			return false
+		}
+		for key, value := range e {
+			if v, ok := a[key]; !ok || !sameStructure(value, v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return false
+		}
+		if len(e) == 0 {
+			// There's nothing to compare items with
+			return true
+		}
+		for _, item := range a {
+			matched := false
+			for _, exampleItem := range e {
+				if sameStructure(exampleItem, item) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.TypeOf(example) == reflect.TypeOf(actual)
+	}
+}
+
+// findOperation returns the first operation with the given ID, or nil.
+func findOperation(swagger *openapi3.T, operationID string) *openapi3.Operation {
+	for _, path := range swagger.Paths.InMatchingOrder() {
+		operations := swagger.Paths.Value(path).Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			if operations[method].OperationID == operationID {
+				return operations[method]
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeJSON round trips v through JSON, so that it can be compared with
+// a decoded JSON document.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
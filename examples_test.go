@@ -0,0 +1,95 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertResponseMatchesExample(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	name := "Marcin"
+	g.GET("/resource", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"name": name, "id": 42})
+	})
+
+	// The handler's output matches the declared example
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, AssertResponseMatchesExample(swagger, "getResource", rec.Code, rec.Body.Bytes()))
+	}
+
+	// Now it doesn't
+	{
+		name = "Someone else"
+		rec := doGet(t, g, "http://deepmap.ai/resource")
+		require.Equal(t, http.StatusOK, rec.Code)
+		err := AssertResponseMatchesExample(swagger, "getResource", rec.Code, rec.Body.Bytes())
+		assert.ErrorIs(t, err, ErrExampleMismatch)
+	}
+
+	// There's no example to compare against
+	{
+		err := AssertResponseMatchesExample(swagger, "getResource", http.StatusNotFound, []byte(`{}`))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrExampleMismatch)
+
+		err = AssertResponseMatchesExample(swagger, "unknownOperation", http.StatusOK, []byte(`{}`))
+		assert.Error(t, err)
+	}
+}
+
+func TestAssertResponseMatchesExampleStructure(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	const operationID = "getExamplesOnlyResource"
+
+	// Values may differ, as long as the structure is that of an example
+	for _, body := range []string{
+		`{"name": "Fido", "tags": ["cat"], "owner": {"id": 12}}`,
+		`{"name": "Fido", "tags": [], "owner": {"id": 12}}`,
+		`{"name": "Fido", "tags": [], "owner": null}`,
+	} {
+		assert.NoError(t, AssertResponseMatchesExampleStructure(swagger, operationID, http.StatusOK, []byte(body)), body)
+	}
+
+	// Missing or extra keys, or values of another type, don't match
+	for _, body := range []string{
+		`{"name": "Fido", "tags": ["cat"]}`,
+		`{"name": "Fido", "tags": ["cat"], "owner": {"id": 12}, "age": 3}`,
+		`{"name": "Fido", "tags": [1], "owner": {"id": 12}}`,
+		`{"name": "Fido", "tags": ["cat"], "owner": {"id": "12"}}`,
+	} {
+		err := AssertResponseMatchesExampleStructure(swagger, operationID, http.StatusOK, []byte(body))
+		assert.ErrorIs(t, err, ErrExampleMismatch, body)
+	}
+
+	// Exact matching still applies to AssertResponseMatchesExample
+	err = AssertResponseMatchesExample(swagger, operationID, http.StatusOK, []byte(`{"name": "Fido", "tags": [], "owner": null}`))
+	assert.ErrorIs(t, err, ErrExampleMismatch)
+}
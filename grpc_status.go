@@ -0,0 +1,116 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+)
+
+// gRPC status codes, see
+// https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+const (
+	grpcCodeInvalidArgument   = 3
+	grpcCodeNotFound          = 5
+	grpcCodePermissionDenied  = 7
+	grpcCodeResourceExhausted = 8
+	grpcCodeUnauthenticated   = 16
+)
+
+// GRPCStatus is the body written by GRPCStatusErrorHandler, in the JSON
+// form of a google.rpc.Status.
+type GRPCStatus struct {
+	Code    int                  `json:"code"`
+	Message string               `json:"message"`
+	Details []GRPCBadRequestInfo `json:"details"`
+}
+
+// GRPCBadRequestInfo is the JSON form of a google.rpc.BadRequest detail.
+type GRPCBadRequestInfo struct {
+	Type            string               `json:"@type"`
+	FieldViolations []GRPCFieldViolation `json:"fieldViolations"`
+}
+
+// GRPCFieldViolation describes a single invalid field of a request.
+type GRPCFieldViolation struct {
+	// Field is the name of the parameter, or the JSON pointer to the value
+	// in the body, which is invalid. It's empty when the error isn't about
+	// a specific field.
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// GRPCStatusErrorHandler is an ErrorHandler which responds with a
+// GRPCStatus, so that APIs served over both REST and gRPC report validation
+// errors consistently. Validation errors map to INVALID_ARGUMENT, with a
+// field violation for each of them.
+func GRPCStatusErrorHandler(c *gin.Context, message string, statusCode int) {
+	var errs []error
+	if me := GetMultiError(c); me != nil {
+		errs = me
+	} else if err := GetValidationError(c); err != nil {
+		errs = []error{err}
+	}
+
+	violations := make([]GRPCFieldViolation, 0, len(errs))
+	for _, err := range errs {
+		violations = append(violations, GRPCFieldViolation{
+			Field:       errorField(err),
+			Description: err.Error(),
+		})
+	}
+
+	c.AbortWithStatusJSON(statusCode, GRPCStatus{
+		Code:    grpcCode(statusCode),
+		Message: message,
+		Details: []GRPCBadRequestInfo{{
+			Type:            "type.googleapis.com/google.rpc.BadRequest",
+			FieldViolations: violations,
+		}},
+	})
+}
+
+// errorField names the request field which err is about.
+func errorField(err error) string {
+	var requestErr *openapi3filter.RequestError
+	if errors.As(err, &requestErr) && requestErr.Parameter != nil {
+		return requestErr.Parameter.Name
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return errorJSONPointer(schemaErr)
+	}
+	return ""
+}
+
+// grpcCode maps an HTTP status code to the closest gRPC status code.
+func grpcCode(statusCode int) int {
+	switch statusCode {
+	case http.StatusNotFound:
+		return grpcCodeNotFound
+	case http.StatusUnauthorized:
+		return grpcCodeUnauthenticated
+	case http.StatusForbidden:
+		return grpcCodePermissionDenied
+	case http.StatusRequestEntityTooLarge:
+		return grpcCodeResourceExhausted
+	default:
+		return grpcCodeInvalidArgument
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCStatusErrorHandler(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		ErrorHandler: GRPCStatusErrorHandler,
+	}))
+	g.POST("/nested_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.GET("/multiparamresource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// A body validation failure names the invalid value
+	{
+		rec := doPost(t, g, "http://deepmap.ai/nested_resource", gin.H{"items": []gin.H{{"id": 0}}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var status GRPCStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.Equal(t, 3, status.Code)
+		assert.Contains(t, status.Message, "request body has an error")
+		require.Len(t, status.Details, 1)
+		assert.Equal(t, "type.googleapis.com/google.rpc.BadRequest", status.Details[0].Type)
+		require.Len(t, status.Details[0].FieldViolations, 1)
+		assert.Equal(t, "/items/0/id", status.Details[0].FieldViolations[0].Field)
+		assert.Contains(t, status.Details[0].FieldViolations[0].Description, "number must be at least 1")
+	}
+
+	// A parameter failure names the parameter
+	{
+		rec := doGet(t, g, "http://deepmap.ai/multiparamresource?id=50")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var status GRPCStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.Equal(t, 3, status.Code)
+		require.Len(t, status.Details, 1)
+		require.Len(t, status.Details[0].FieldViolations, 1)
+		assert.Equal(t, "id2", status.Details[0].FieldViolations[0].Field)
+	}
+
+	// Unmatched routes map to NOT_FOUND
+	{
+		rec := doGet(t, g, "http://deepmap.ai/nonexistent")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		var status GRPCStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+		assert.Equal(t, 5, status.Code)
+		require.Len(t, status.Details[0].FieldViolations, 1)
+		assert.Equal(t, "", status.Details[0].FieldViolations[0].Field)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// checkJSONDepth streams a JSON request body, failing as soon as objects or
+// arrays are nested deeper than maxDepth, before the body is decoded in full
+// for schema validation. The body is left readable. Malformed JSON is left to
+// the schema validation to report.
+func checkJSONDepth(req *http.Request, route *routers.Route, maxDepth int) error {
+	if !hasBody(req) {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	var read bytes.Buffer
+	body := req.Body
+	defer func() {
+		req.Body = readCloser{io.MultiReader(&read, body), body}
+	}()
+
+	decoder := json.NewDecoder(io.TeeReader(body, &read))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			if depth++; depth > maxDepth {
+				requestErr := &openapi3filter.RequestError{
+					Reason: fmt.Sprintf("maximum JSON nesting depth is %d", maxDepth),
+				}
+				if route.Operation.RequestBody != nil {
+					requestErr.RequestBody = route.Operation.RequestBody.Value
+				}
+				return requestErr
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MutuallyExclusiveExtension is the operation extension listing groups of
+// query parameters of which at most one may be set, such as:
+//
+//	x-mutually-exclusive:
+//	  - [after, before]
+//
+// A single group may also be given as a flat list of names.
+const MutuallyExclusiveExtension = "x-mutually-exclusive"
+
+// validateMutuallyExclusiveParams returns an error if req sets more than one
+// query parameter of one of the groups, parsed from the operation's
+// x-mutually-exclusive extension.
+func validateMutuallyExclusiveParams(req *http.Request, groups [][]string) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	query := req.URL.Query()
+	for _, group := range groups {
+		var present []string
+		for _, name := range group {
+			if _, ok := query[name]; ok {
+				present = append(present, fmt.Sprintf("%q", name))
+			}
+		}
+		if len(present) > 1 {
+			return fmt.Errorf("query parameters %s are mutually exclusive", strings.Join(present, ", "))
+		}
+	}
+	return nil
+}
+
+// mutuallyExclusiveGroups parses the value of an x-mutually-exclusive
+// extension, which is either a list of names or a list of lists of names.
+func mutuallyExclusiveGroups(raw interface{}) ([][]string, error) {
+	invalid := fmt.Errorf("%s must be a list of parameter names, or a list of such lists", MutuallyExclusiveExtension)
+
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, invalid
+	}
+	var flat []string
+	var groups [][]string
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			flat = append(flat, v)
+		case []interface{}:
+			group := make([]string, 0, len(v))
+			for _, name := range v {
+				s, ok := name.(string)
+				if !ok {
+					return nil, invalid
+				}
+				group = append(group, s)
+			}
+			groups = append(groups, group)
+		default:
+			return nil, invalid
+		}
+	}
+	if len(flat) > 0 {
+		groups = append(groups, flat)
+	}
+	return groups, nil
+}
@@ -15,34 +15,73 @@
 package ginmiddleware
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
-	"github.com/getkin/kin-openapi/routers/gorillamux"
 	"github.com/gin-gonic/gin"
 )
 
 const (
 	GinContextKey = "oapi-codegen/gin-context"
 	UserDataKey   = "oapi-codegen/user-data"
+	// OperationExtensionKeyPrefix prefixes the gin context keys under which
+	// Options.OperationExtensions are stored
+	OperationExtensionKeyPrefix = "oapi-codegen/operation-extension/"
+	// MultiErrorKey is the gin context key under which the raw
+	// openapi3.MultiError of a failed validation is stored
+	MultiErrorKey = "oapi-codegen/multi-error"
+	// ValidationErrorKey is the gin context key under which the error of a
+	// failed validation is stored, before the error handler is called
+	ValidationErrorKey = "oapi-codegen/validation-error"
+	// MatchedRouteKey is the gin context key under which the route a request
+	// matched in the spec is stored
+	MatchedRouteKey = "oapi-codegen/matched-route"
+)
+
+var (
+	// ErrRequestBodyTooLarge is returned when a request body exceeds
+	// Options.MaxRequestBodyBytes
+	ErrRequestBodyTooLarge = errors.New("request body too large")
+	// ErrMissingContentType is returned when a request has a body but no
+	// Content-Type header, and Options.DefaultRequestContentType isn't set
+	ErrMissingContentType = errors.New("request body has an error: missing Content-Type header")
 )
 
 // OapiValidatorFromYamlFile creates a validator middleware from a YAML file path
 func OapiValidatorFromYamlFile(path string) (gin.HandlerFunc, error) {
+	return OapiValidatorFromYamlFileWithLoader(path, openapi3.NewLoader())
+}
+
+// OapiValidatorFromYamlFileWithLoader creates a validator middleware from a
+// YAML file path, using the given loader. This allows controlling how
+// external references are resolved, for instance setting
+// IsExternalRefsAllowed and a ReadFromURIFunc which reads them from a local
+// mirror.
+func OapiValidatorFromYamlFileWithLoader(path string, loader *openapi3.Loader) (gin.HandlerFunc, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("error reading %s: %s", path, err)
 	}
 
-	swagger, err := openapi3.NewLoader().LoadFromData(data)
+	swagger, err := loader.LoadFromDataWithPath(stripBOM(data), &url.URL{Path: filepath.ToSlash(path)})
 	if err != nil {
 		return nil, fmt.Errorf("error parsing %s as Swagger YAML: %s",
 			path, err)
@@ -50,6 +89,101 @@ func OapiValidatorFromYamlFile(path string) (gin.HandlerFunc, error) {
 	return OapiRequestValidator(swagger), nil
 }
 
+// OapiValidatorFromYamlDataWithBase creates a validator middleware from a
+// YAML spec, resolving relative external references, such as
+// ./schemas/user.yaml, against base. This is either a directory or an
+// absolute URI, such as https://schemas.example.com/api/.
+func OapiValidatorFromYamlDataWithBase(data []byte, base string) (gin.HandlerFunc, error) {
+	location := &url.URL{Path: filepath.ToSlash(filepath.Join(base, "spec.yaml"))}
+	// A single letter scheme is a Windows drive
+	if u, err := url.Parse(base); err == nil && len(u.Scheme) > 1 {
+		location = u.JoinPath("spec.yaml")
+	}
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	swagger, err := loader.LoadFromDataWithPath(stripBOM(data), location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing spec as Swagger YAML, relative to %s: %s", base, err)
+	}
+	return OapiRequestValidator(swagger), nil
+}
+
+// OapiRequestValidatorFromURL creates a validator middleware from a spec
+// served at rawURL. Transient failures, network errors and 5xx responses,
+// are retried up to attempts times in total, waiting baseDelay before the
+// first retry and doubling the delay after each one. Retries stop early when
+// ctx is done.
+func OapiRequestValidatorFromURL(ctx context.Context, rawURL string, attempts int, baseDelay time.Duration) (gin.HandlerFunc, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing URL %s: %w", rawURL, err)
+	}
+
+	var data []byte
+	delay := baseDelay
+	for attempt := 1; ; attempt++ {
+		var retryable bool
+		data, retryable, err = fetchSpec(ctx, rawURL)
+		if err == nil || !retryable || attempt >= attempts {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("error loading %s: %w (last error: %s)", rawURL, ctx.Err(), err)
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", rawURL, err)
+	}
+
+	loader := openapi3.NewLoader()
+	loader.Context = ctx
+	swagger, err := loader.LoadFromDataWithPath(stripBOM(data), u)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s as Swagger YAML: %s",
+			rawURL, err)
+	}
+	return OapiRequestValidator(swagger), nil
+}
+
+// fetchSpec downloads a spec, reporting whether a failure may be transient.
+func fetchSpec(ctx context.Context, rawURL string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	return data, false, nil
+}
+
+// utf8BOM is the byte order mark some editors prepend to UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// stripBOM removes a leading UTF-8 byte order mark, which the spec loader
+// would otherwise fail to parse.
+func stripBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
+
 // OapiRequestValidator is an gin middleware function which validates incoming HTTP requests
 // to make sure that they conform to the given OAPI 3.0 specification. When
 // OAPI validation fails on the request, we return an HTTP/400 with error message
@@ -63,6 +197,67 @@ type ErrorHandler func(c *gin.Context, message string, statusCode int)
 // MultiErrorHandler is called when oapi returns a MultiError type
 type MultiErrorHandler func(openapi3.MultiError) error
 
+// AuditEntry records the outcome of a request, see Options.AuditLogger.
+type AuditEntry struct {
+	// Time the request was received at
+	Time time.Time
+	// OperationID of the operation the request matched, if any
+	OperationID string
+	Method      string
+	Path        string
+	// Status of the response
+	Status int
+	// Valid is whether the request passed validation, Error holds the
+	// validation error otherwise
+	Valid bool
+	Error error
+	// Skipped tells why the request wasn't validated, if it wasn't. Valid
+	// is then false, and Error only holds the error of unmatched requests
+	// let through by Options.PassThroughUnmatched.
+	Skipped SkipReason
+}
+
+// SkipReason tells why a request wasn't validated, see AuditEntry.Skipped.
+type SkipReason string
+
+const (
+	// SkippedBySkipper requests were passed on by Options.Skipper
+	SkippedBySkipper SkipReason = "skipper"
+	// SkippedDisabled requests arrived while validation was disabled, see
+	// SetEnabled
+	SkippedDisabled SkipReason = "disabled"
+	// SkippedSpecPath requests were for Options.SpecPath
+	SkippedSpecPath SkipReason = "spec-path"
+	// SkippedUnmatched requests matched no operation, and were let through
+	// by Options.PassThroughUnmatched
+	SkippedUnmatched SkipReason = "unmatched"
+	// SkippedValidatedOnce requests were to an operation already validated,
+	// with Options.ValidateOncePerRoute
+	SkippedValidatedOnce SkipReason = "validated-once"
+	// SkippedByExtension requests were to an operation whose x-validation
+	// extension asks to skip them, see ValidationExtension
+	SkippedByExtension SkipReason = "extension"
+)
+
+// AuditLogger records an AuditEntry for a request, see Options.AuditLogger.
+type AuditLogger func(c *gin.Context, entry AuditEntry)
+
+// ChainAuditLoggers returns an AuditLogger calling each of loggers in turn,
+// so that a ValidationRecorder can be used alongside another logger. Nil
+// loggers are ignored.
+func ChainAuditLoggers(loggers ...AuditLogger) AuditLogger {
+	return func(c *gin.Context, entry AuditEntry) {
+		for _, logger := range loggers {
+			if logger != nil {
+				logger(c, entry)
+			}
+		}
+	}
+}
+
+// RequestValidatedFunc is called after a request has been successfully validated
+type RequestValidatedFunc func(c *gin.Context, operationID string, d time.Duration)
+
 // Options to customize request validation. These are passed through to
 // openapi3filter.
 type Options struct {
@@ -73,47 +268,462 @@ type Options struct {
 	MultiErrorHandler MultiErrorHandler
 	// SilenceServersWarning allows silencing a warning for https://github.com/deepmap/oapi-codegen/issues/882 that reports when an OpenAPI spec has `spec.Servers != nil`
 	SilenceServersWarning bool
+	// OnRequestValidated, if set, is called after a request passed
+	// validation, with the matched operation ID and the time validation took
+	OnRequestValidated RequestValidatedFunc
+	// RouteNotFoundHandler, if set, is called instead of ErrorHandler when
+	// the request doesn't match any operation in the spec. See
+	// JSONRouteNotFoundHandler for a ready-made one.
+	RouteNotFoundHandler ErrorHandler
+	// CollectAllErrors enables openapi3filter's MultiError option, so that
+	// every validation error is reported through the MultiErrorHandler
+	// rather than only the first one.
+	CollectAllErrors bool
+	// MaxRequestBodyBytes, if positive, limits how much of the request body
+	// is read. Larger bodies are rejected with an HTTP/413.
+	MaxRequestBodyBytes int64
+	// IncludeXWebhooks makes the path items in the spec's x-webhooks
+	// extension routable, so that webhook payloads are validated too.
+	IncludeXWebhooks bool
+	// TrustForwardedHost makes the router match the spec's servers against
+	// the X-Forwarded-Host and X-Forwarded-Proto headers, when present,
+	// rather than the Host the request was received on. Only enable this
+	// behind a proxy which sets these headers.
+	TrustForwardedHost bool
+	// MaxArrayItemErrors, if positive, changes how request bodies which are
+	// JSON arrays are validated: items are validated one at a time, and
+	// validation stops once this many invalid items were found. The invalid
+	// indices are reported in an ArrayItemsError.
+	MaxArrayItemErrors int
+	// StatusCodeResolver, if set, chooses the status code for a validation
+	// error, overriding the default 400/404/413 selection. Returning 0 keeps
+	// the default.
+	StatusCodeResolver func(err error) int
+	// ErrorPointerHeader, if set, names a response header which is set to
+	// the JSON pointer of the value which failed schema validation, such as
+	// `/items/2/id`, when there is one.
+	ErrorPointerHeader string
+	// OperationExtensions lists spec extensions, such as `x-gin-handler`,
+	// whose values on the matched operation are stored in the gin context
+	// for handlers to read with GetOperationExtension.
+	OperationExtensions []string
+	// DefaultRequestContentType is assumed for requests which have a body
+	// but no Content-Type header, such as `application/json`. When empty,
+	// these requests are rejected with ErrMissingContentType.
+	DefaultRequestContentType string
+	// EnforceMutuallyExclusiveParams rejects requests which set more than
+	// one of a group of query parameters declared mutually exclusive in the
+	// operation's x-mutually-exclusive extension.
+	EnforceMutuallyExclusiveParams bool
+	// SkipSecurityValidation accepts every security requirement without
+	// calling Options.AuthenticationFunc, for when authentication is handled
+	// elsewhere. Parameters and bodies are still validated.
+	SkipSecurityValidation bool
+	// RejectUnknownHeaders rejects requests carrying headers which the
+	// matched operation doesn't declare as parameters or security schemes.
+	// Standard HTTP headers, such as Accept or User-Agent, and those listed
+	// in AllowedHeaders are always accepted.
+	RejectUnknownHeaders bool
+	// AllowedHeaders lists further headers accepted by RejectUnknownHeaders,
+	// such as those added by proxies. Names ending with * match any header
+	// starting with the rest, such as X-Amzn-*.
+	AllowedHeaders []string
+	// RecordErrorInContext also adds validation errors to c.Errors, for
+	// logging middleware, before the error response is rendered.
+	RecordErrorInContext bool
+	// UseValidationExtension lets operations override these options with an
+	// x-validation extension, see ValidationExtension.
+	UseValidationExtension bool
+	// ContextFunc, if set, creates the context passed to openapi3filter and
+	// on to callbacks such as the AuthenticationFunc, instead of
+	// context.Background(). The gin context and UserData are still added to
+	// it. When it returns nil, the request's context is used.
+	ContextFunc func(c *gin.Context) context.Context
+	// ValidateOncePerRoute validates requests to each operation until one
+	// passes, and lets later ones through untouched. It's meant for load
+	// tests, to catch configuration errors without the cost of validating
+	// every request, not for production use.
+	ValidateOncePerRoute bool
+	// SpecPath, if set, is the path the service serves its own spec at, such
+	// as `/openapi.yaml`. Requests to it aren't validated, as the spec
+	// usually doesn't model it.
+	SpecPath string
+	// PassThroughUnmatched lets requests which don't match an operation of
+	// the spec through to the next handler, rather than rejecting them, for
+	// engines which also serve routes not modeled in the spec.
+	PassThroughUnmatched bool
+	// ErrorJSONKey is the key of the message in the default error body,
+	// `error` when empty.
+	ErrorJSONKey string
+	// AuditLogger, if set, is called with an AuditEntry for every request,
+	// whether it passed validation, failed it or was skipped, once the rest
+	// of the chain has handled it. Use ChainAuditLoggers to set several.
+	AuditLogger AuditLogger
+	// CollapseRepeatedErrors merges consecutive identical errors of a
+	// MultiError, and consecutive array items failing with the same error
+	// in an ArrayItemsError, into one message with a count.
+	CollapseRepeatedErrors bool
+	// HostResolver, if set, returns the host the router matches against the
+	// spec's servers, for setups which derive it from something other than
+	// the Host header, such as a path segment or a custom header. Returning
+	// an empty string keeps the default behaviour. It takes precedence over
+	// TrustForwardedHost.
+	HostResolver func(c *gin.Context) string
+	// StreamArrayMaxItems counts the items of JSON array request bodies
+	// whose schema sets maxItems as they are read, rejecting the request as
+	// soon as there are too many, rather than after reading all of it.
+	StreamArrayMaxItems bool
+	// RejectDeprecatedParams rejects requests which set a query, header or
+	// cookie parameter marked deprecated in the spec.
+	RejectDeprecatedParams bool
+	// MaxJSONDepth, if positive, rejects JSON request bodies whose objects
+	// and arrays are nested deeper than this, before they're decoded for
+	// schema validation, to guard against deeply nested payloads.
+	MaxJSONDepth int
+	// Skipper, if set, is called first for every request; requests for
+	// which it returns true are passed on without any validation, such as
+	// health checks which aren't part of the spec.
+	Skipper func(c *gin.Context) bool
+	// StoreValidatedParams stores the typed values of the parameters of
+	// requests which pass validation in the gin context, so that handlers
+	// needn't parse them again. See GetValidatedParams.
+	StoreValidatedParams bool
+	// ErrorTemplate, if set, renders the body of error responses, as JSON,
+	// in place of the default one. It's executed with an ErrorTemplateData,
+	// see ErrorTemplateFuncs for helpers. ErrorHandler takes precedence.
+	ErrorTemplate *template.Template
+	// ProblemJSON renders error responses as RFC 7807 problem documents,
+	// with the application/problem+json content type, rather than the
+	// default body. ErrorHandler and ErrorTemplate take precedence.
+	ProblemJSON bool
+	// DisableContextInjection leaves the gin context and UserData out of the
+	// context passed to openapi3filter, saving an allocation or two per
+	// request. GetGinContext and GetUserData then return nil.
+	DisableContextInjection bool
+}
+
+// ProblemDetails is the RFC 7807 problem document rendered for failed
+// requests when Options.ProblemJSON is set.
+type ProblemDetails struct {
+	// Type is a URI identifying the problem type, "about:blank" as the
+	// problem is described by the status code alone
+	Type string `json:"type"`
+	// Title is the text of the status code
+	Title string `json:"title"`
+	// Status is the status code of the response
+	Status int `json:"status"`
+	// Detail is the validation error message
+	Detail string `json:"detail"`
 }
 
-// OapiRequestValidatorWithOptions creates a validator from a swagger object, with validation options
+// ErrorTemplateData is what Options.ErrorTemplate is executed with.
+type ErrorTemplateData struct {
+	// Error is the validation error message
+	Error string
+	// Status is the status code of the response
+	Status int
+	// OperationID of the operation the request matched, if any
+	OperationID string
+}
+
+// ErrorTemplateFuncs are helpers for Options.ErrorTemplate. Add them with
+// Funcs before parsing the template. json encodes a value as JSON, such as
+// `{"message": {{json .Error}}}`.
+var ErrorTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// disabled turns every validator middleware into a pass-through, see
+// SetEnabled.
+var disabled atomic.Bool
+
+// SetEnabled enables or disables, at runtime, the validation done by every
+// middleware created by this package. It's a kill switch, meant to be wired
+// to an admin endpoint or a feature flag. Validation is enabled by default.
+func SetEnabled(enabled bool) {
+	disabled.Store(!enabled)
+}
+
+// Enabled reports whether validation is enabled, see SetEnabled.
+func Enabled() bool {
+	return !disabled.Load()
+}
+
+// OapiRequestValidatorWithOptions creates a validator from a swagger object, with validation options.
+// It panics if no router can be built for the spec, see NewOapiRequestValidator.
 func OapiRequestValidatorWithOptions(swagger *openapi3.T, options *Options) gin.HandlerFunc {
+	validator, err := NewOapiRequestValidator(swagger, options)
+	if err != nil {
+		panic(err)
+	}
+	return validator
+}
+
+// NewOapiRequestValidator creates a validator from a swagger object, with
+// validation options, like OapiRequestValidatorWithOptions, but returns an
+// error rather than panicking when no router can be built for the spec.
+func NewOapiRequestValidator(swagger *openapi3.T, options *Options) (gin.HandlerFunc, error) {
 	if swagger.Servers != nil && (options == nil || !options.SilenceServersWarning) {
 		log.Println("WARN: OapiRequestValidatorWithOptions called with an OpenAPI spec that has `Servers` set. This may lead to an HTTP 400 with `no matching operation was found` when sending a valid request, as the validator performs `Host` header validation. If you're expecting `Host` header validation, you can silence this warning by setting `Options.SilenceServersWarning = true`. See https://github.com/deepmap/oapi-codegen/issues/882 for more information.")
 	}
 
-	router, err := gorillamux.NewRouter(swagger)
+	if options != nil && options.IncludeXWebhooks {
+		var err error
+		if swagger, err = withXWebhooks(swagger); err != nil {
+			return nil, err
+		}
+	}
+
+	router, err := newRouter(swagger)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("error building router: %w", err)
+	}
+	extensions, err := parseSpecExtensions(swagger, options)
+	if err != nil {
+		return nil, err
 	}
+	// seen holds the operations validated once, for ValidateOncePerRoute
+	var seen sync.Map
 	return func(c *gin.Context) {
-		err := ValidateRequestFromContext(c, router, options)
-		if err != nil {
-			// using errors.Is did not work
-			if options != nil && options.ErrorHandler != nil && err.Error() == routers.ErrPathNotFound.Error() {
-				options.ErrorHandler(c, err.Error(), http.StatusNotFound)
-				// in case the handler didn't internally call Abort, stop the chain
-				c.Abort()
-			} else if options != nil && options.ErrorHandler != nil {
-				options.ErrorHandler(c, err.Error(), http.StatusBadRequest)
-				// in case the handler didn't internally call Abort, stop the chain
-				c.Abort()
-			} else if err.Error() == routers.ErrPathNotFound.Error() {
-				// note: i am not sure if this is the best way to handle this
-				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			} else {
-				// note: i am not sure if this is the best way to handle this
-				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		start := time.Now()
+		var route *routers.Route
+		var err error
+		var skipped SkipReason
+		switch {
+		case options != nil && options.Skipper != nil && options.Skipper(c):
+			skipped = SkippedBySkipper
+		case disabled.Load():
+			skipped = SkippedDisabled
+		case options != nil && options.SpecPath != "" && c.Request.URL.Path == options.SpecPath:
+			skipped = SkippedSpecPath
+		default:
+			var pathParams map[string]string
+			route, pathParams, err = findRoute(c, router, options)
+			if err != nil {
+				if options != nil && options.PassThroughUnmatched && isRouteError(err) {
+					skipped = SkippedUnmatched
+				}
+				break
+			}
+			// validateRoute would pass these too, without validating them
+			if parsed, parseErr := extensions.forOperation(route.Operation, options); parseErr == nil && parsed.validation.SkipRequest {
+				skipped = SkippedByExtension
+				break
+			}
+			// Operations are told apart by method and path, as their
+			// operationId is optional, and not always unique in practice
+			validateOnce := options != nil && options.ValidateOncePerRoute
+			var key string
+			if validateOnce {
+				key = route.Method + " " + route.Path
+				if _, ok := seen.Load(key); ok {
+					skipped = SkippedValidatedOnce
+					break
+				}
+			}
+			err = validateRoute(c, route, pathParams, options, extensions)
+			// Only a request which passed validation marks its route, so
+			// that invalid ones don't exempt the requests after them
+			if validateOnce && err == nil {
+				seen.Store(key, struct{}{})
 			}
 		}
+
+		if route != nil && options != nil {
+			for _, name := range options.OperationExtensions {
+				if value, ok := route.Operation.Extensions[name]; ok {
+					c.Set(OperationExtensionKeyPrefix+name, value)
+				}
+			}
+		}
+		if skipped == "" && err == nil && options != nil {
+			if options.OnRequestValidated != nil {
+				options.OnRequestValidated(c, route.Operation.OperationID, time.Since(start))
+			}
+		}
+		if skipped == "" && err != nil {
+			handleValidationError(c, route, err, options)
+		}
 		c.Next()
+
+		if options != nil && options.AuditLogger != nil {
+			entry := AuditEntry{
+				Time:    start,
+				Method:  c.Request.Method,
+				Path:    c.Request.URL.Path,
+				Status:  c.Writer.Status(),
+				Valid:   skipped == "" && err == nil,
+				Error:   err,
+				Skipped: skipped,
+			}
+			if route != nil && route.Operation != nil {
+				entry.OperationID = route.Operation.OperationID
+			}
+			options.AuditLogger(c, entry)
+		}
+	}, nil
+}
+
+// isRouteError reports whether err is caused by the request not matching
+// any operation of the spec.
+func isRouteError(err error) bool {
+	var routeErr *routers.RouteError
+	return errors.As(err, &routeErr)
+}
+
+// handleValidationError renders a validation error through the handlers
+// configured in options, falling back to a JSON body with the error message.
+func handleValidationError(c *gin.Context, route *routers.Route, err error, options *Options) {
+	statusCode := http.StatusBadRequest
+	// using errors.Is did not work
+	if err.Error() == routers.ErrPathNotFound.Error() {
+		statusCode = http.StatusNotFound
+	} else if errors.Is(err, ErrRequestBodyTooLarge) {
+		statusCode = http.StatusRequestEntityTooLarge
+	}
+	if options != nil && options.StatusCodeResolver != nil {
+		if resolved := options.StatusCodeResolver(err); resolved != 0 {
+			statusCode = resolved
+		}
+	}
+
+	c.Set(ValidationErrorKey, err)
+
+	if options != nil && options.RecordErrorInContext {
+		_ = c.Error(err)
+	}
+
+	if options != nil && options.ErrorPointerHeader != "" {
+		if pointer := errorJSONPointer(err); pointer != "" {
+			c.Header(options.ErrorPointerHeader, pointer)
+		}
+	}
+
+	if options != nil && options.RouteNotFoundHandler != nil && statusCode == http.StatusNotFound {
+		options.RouteNotFoundHandler(c, err.Error(), statusCode)
+		// in case the handler didn't internally call Abort, stop the chain
+		c.Abort()
+	} else if options != nil && options.ErrorHandler != nil {
+		options.ErrorHandler(c, err.Error(), statusCode)
+		// in case the handler didn't internally call Abort, stop the chain
+		c.Abort()
+	} else if body, ok := renderErrorTemplate(route, err, statusCode, options); ok {
+		c.Data(statusCode, "application/json; charset=utf-8", body)
+		c.Abort()
+	} else if options != nil && options.ProblemJSON {
+		body, _ := json.Marshal(ProblemDetails{
+			Type:   "about:blank",
+			Title:  http.StatusText(statusCode),
+			Status: statusCode,
+			Detail: err.Error(),
+		})
+		c.Data(statusCode, "application/problem+json", body)
+		c.Abort()
+	} else {
+		// note: i am not sure if this is the best way to handle this
+		key := "error"
+		if options != nil && options.ErrorJSONKey != "" {
+			key = options.ErrorJSONKey
+		}
+		c.AbortWithStatusJSON(statusCode, gin.H{key: err.Error()})
+	}
+}
+
+// renderErrorTemplate executes Options.ErrorTemplate, if any. It returns false
+// when there's no template or it fails, so the default body is used instead.
+func renderErrorTemplate(route *routers.Route, err error, statusCode int, options *Options) ([]byte, bool) {
+	if options == nil || options.ErrorTemplate == nil {
+		return nil, false
+	}
+	data := ErrorTemplateData{Error: err.Error(), Status: statusCode}
+	if route != nil && route.Operation != nil {
+		data.OperationID = route.Operation.OperationID
+	}
+	var body bytes.Buffer
+	if tmplErr := options.ErrorTemplate.Execute(&body, data); tmplErr != nil {
+		log.Printf("WARN: error executing ErrorTemplate: %s", tmplErr)
+		return nil, false
+	}
+	return body.Bytes(), true
+}
+
+// jsonPointerEscaper escapes JSON pointer reference tokens, see RFC 6901
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// errorJSONPointer returns the JSON pointer to the value which failed schema
+// validation, or an empty string if err isn't a schema error.
+func errorJSONPointer(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return ""
+	}
+	var pointer strings.Builder
+	for _, token := range schemaErr.JSONPointer() {
+		pointer.WriteString("/")
+		pointer.WriteString(jsonPointerEscaper.Replace(token))
 	}
+	return pointer.String()
+}
+
+// RouteNotFoundResponse is the body written by JSONRouteNotFoundHandler
+type RouteNotFoundResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+}
+
+// JSONRouteNotFoundHandler is a RouteNotFoundHandler which responds with a
+// RouteNotFoundResponse, describing the request which could not be matched.
+func JSONRouteNotFoundHandler(c *gin.Context, message string, statusCode int) {
+	c.AbortWithStatusJSON(statusCode, RouteNotFoundResponse{
+		Status:  statusCode,
+		Message: message,
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+	})
 }
 
 // ValidateRequestFromContext is called from the middleware above and actually does the work
 // of validating a request.
 func ValidateRequestFromContext(c *gin.Context, router routers.Router, options *Options) error {
-	req := c.Request
-	route, pathParams, err := router.FindRoute(req)
+	_, err := validateRequest(c, router, options, nil)
+	return err
+}
+
+// validationError presents a shortened message for an error returned by
+// openapi3filter, while keeping the original error available to errors.Is
+// and errors.As.
+type validationError struct {
+	message string
+	err     error
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+// validateRequest validates the request and returns the route it was
+// matched against, if any, even when validation fails.
+func validateRequest(c *gin.Context, router routers.Router, options *Options, extensions specExtensions) (*routers.Route, error) {
+	route, pathParams, err := findRoute(c, router, options)
+	if err != nil {
+		return nil, err
+	}
+	return route, validateRoute(c, route, pathParams, options, extensions)
+}
+
+// findRoute finds the route the request matches, and stores it in the gin
+// context.
+func findRoute(c *gin.Context, router routers.Router, options *Options) (*routers.Route, map[string]string, error) {
+	route, pathParams, err := router.FindRoute(routingRequest(c, options))
 
 	// We failed to find a matching route for the request.
 	if err != nil {
@@ -121,13 +731,64 @@ func ValidateRequestFromContext(c *gin.Context, router routers.Router, options *
 		case *routers.RouteError:
 			// We've got a bad request, the path requested doesn't match
 			// either server, or path, or something.
-			return errors.New(e.Reason)
+			return nil, nil, &validationError{message: e.Reason, err: e}
 		default:
 			// This should never happen today, but if our upstream code changes,
 			// we don't want to crash the server, so handle the unexpected error.
-			return fmt.Errorf("error validating route: %s", err.Error())
+			return nil, nil, fmt.Errorf("error validating route: %s", err.Error())
 		}
 	}
+	c.Set(MatchedRouteKey, route)
+	return route, pathParams, nil
+}
+
+// validateRoute validates the request against the route it matched.
+func validateRoute(c *gin.Context, route *routers.Route, pathParams map[string]string, options *Options, extensions specExtensions) error {
+	req := c.Request
+	parsed, err := extensions.forOperation(route.Operation, options)
+	if err != nil {
+		return err
+	}
+
+	overrides := parsed.validation
+	if overrides.SkipRequest {
+		return nil
+	}
+	excludeRequestBody := overrides.ExcludeRequestBody || (options != nil && options.Options.ExcludeRequestBody)
+
+	// Header parameters are looked up by canonical key, so a request whose
+	// header map has other keys is validated through a copy with canonical
+	// ones, leaving the handlers' request as it was sent. The body, which
+	// validation reads and replaces, is handed back.
+	if header, ok := canonicalHeader(req.Header); ok {
+		validated := *req
+		validated.Header = header
+		req = &validated
+		defer func() { c.Request.Body = req.Body }()
+	}
+	normalizeContentType(req.Header)
+
+	if options != nil && options.RejectUnknownHeaders {
+		if err := validateKnownHeaders(req.Header, route, options.AllowedHeaders); err != nil {
+			return err
+		}
+	}
+
+	if options != nil && options.MaxRequestBodyBytes > 0 && req.Body != nil {
+		if req.ContentLength > options.MaxRequestBodyBytes {
+			return fmt.Errorf("%w: limit is %d bytes", ErrRequestBodyTooLarge, options.MaxRequestBodyBytes)
+		}
+		req.Body = http.MaxBytesReader(c.Writer, req.Body, options.MaxRequestBodyBytes)
+	}
+
+	if route.Operation.RequestBody != nil && hasBody(req) && req.Header.Get("Content-Type") == "" &&
+		!excludeRequestBody {
+		if options == nil || options.DefaultRequestContentType == "" {
+			return ErrMissingContentType
+		}
+		req.Header.Set("Content-Type", options.DefaultRequestContentType)
+		c.Request.Header.Set("Content-Type", options.DefaultRequestContentType)
+	}
 
 	validationInput := &openapi3filter.RequestValidationInput{
 		Request:    req,
@@ -137,19 +798,75 @@ func ValidateRequestFromContext(c *gin.Context, router routers.Router, options *
 
 	// Pass the gin context into the request validator, so that any callbacks
 	// which it invokes make it available.
-	requestContext := context.WithValue(context.Background(), GinContextKey, c) //nolint:staticcheck
+	baseContext := context.Background()
+	if options != nil && options.ContextFunc != nil {
+		if ctx := options.ContextFunc(c); ctx != nil {
+			baseContext = ctx
+		} else {
+			baseContext = c.Request.Context()
+		}
+	}
+	requestContext := baseContext
+	if options == nil || !options.DisableContextInjection {
+		requestContext = context.WithValue(requestContext, GinContextKey, c) //nolint:staticcheck
+	}
+
+	if options != nil && options.MaxJSONDepth > 0 && !excludeRequestBody {
+		if err := checkJSONDepth(req, route, options.MaxJSONDepth); err != nil {
+			return err
+		}
+	}
+
+	if options != nil && options.StreamArrayMaxItems && !excludeRequestBody {
+		if err := checkArrayMaxItems(req, route); err != nil {
+			return err
+		}
+	}
+
+	// A JSON array body is validated after the rest of the request, by
+	// validateArrayItems, when MaxArrayItemErrors is set.
+	var arraySchema *openapi3.Schema
+	if options != nil && options.MaxArrayItemErrors > 0 && !excludeRequestBody {
+		arraySchema = arrayBodySchema(req, route)
+	}
 
 	if options != nil {
 		validationInput.Options = &options.Options
+		if (options.CollectAllErrors && !options.Options.MultiError) || arraySchema != nil || options.SkipSecurityValidation ||
+			overrides != (operationValidation{}) {
+			filterOptions := options.Options
+			filterOptions.MultiError = filterOptions.MultiError || options.CollectAllErrors
+			filterOptions.ExcludeRequestBody = excludeRequestBody || arraySchema != nil
+			filterOptions.ExcludeRequestQueryParams = filterOptions.ExcludeRequestQueryParams || overrides.ExcludeRequestQueryParams
+			if options.SkipSecurityValidation {
+				filterOptions.AuthenticationFunc = openapi3filter.NoopAuthenticationFunc
+			}
+			validationInput.Options = &filterOptions
+		}
 		validationInput.ParamDecoder = options.ParamDecoder
-		requestContext = context.WithValue(requestContext, UserDataKey, options.UserData) //nolint:staticcheck
+		// GetUserData returns nil for a missing value, so there's no need
+		// to allocate a context for a nil one
+		if options.UserData != nil && !options.DisableContextInjection {
+			requestContext = context.WithValue(requestContext, UserDataKey, options.UserData) //nolint:staticcheck
+		}
 	}
 
 	err = openapi3filter.ValidateRequest(requestContext, validationInput)
 	if err != nil {
-		me := openapi3.MultiError{}
-		if errors.As(err, &me) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrRequestBodyTooLarge, maxBytesErr.Limit)
+		}
+
+		// Only a MultiError returned at the top level comes from the
+		// MultiError option; schema errors, such as those of oneOf, may wrap
+		// one of their own.
+		if me, ok := err.(openapi3.MultiError); ok {
+			c.Set(MultiErrorKey, me)
 			errFunc := getMultiErrorHandlerFromOptions(options)
+			if options != nil && options.CollapseRepeatedErrors {
+				me = collapseMultiError(me)
+			}
 			return errFunc(me)
 		}
 
@@ -159,18 +876,176 @@ func ValidateRequestFromContext(c *gin.Context, router routers.Router, options *
 			// Split up the verbose error by lines and return the first one
 			// openapi errors seem to be multi-line with a decent message on the first
 			errorLines := strings.Split(e.Error(), "\n")
-			return fmt.Errorf("error in openapi3filter.RequestError: %s", errorLines[0])
+			return &validationError{
+				message: fmt.Sprintf("error in openapi3filter.RequestError: %s", errorLines[0]),
+				err:     e,
+			}
 		case *openapi3filter.SecurityRequirementsError:
-			return fmt.Errorf("error in openapi3filter.SecurityRequirementsError: %s", e.Error())
+			return fmt.Errorf("error in openapi3filter.SecurityRequirementsError: %w", e)
 		default:
 			// This should never happen today, but if our upstream code changes,
 			// we don't want to crash the server, so handle the unexpected error.
 			return fmt.Errorf("error validating request: %w", err)
 		}
 	}
+
+	if err := validateRepeatedPathParams(requestContext, validationInput); err != nil {
+		return err
+	}
+
+	if options != nil && options.RejectDeprecatedParams {
+		if err := validateNoDeprecatedParams(req, route); err != nil {
+			return err
+		}
+	}
+
+	if options != nil && options.EnforceMutuallyExclusiveParams {
+		if err := validateMutuallyExclusiveParams(req, parsed.mutuallyExclusive); err != nil {
+			return err
+		}
+	}
+
+	if arraySchema != nil {
+		if err := validateArrayItems(req, arraySchema, route.Operation.RequestBody.Value.Required, options.MaxArrayItemErrors); err != nil {
+			var itemsErr *ArrayItemsError
+			if options.CollapseRepeatedErrors && errors.As(err, &itemsErr) {
+				itemsErr.collapse = true
+			}
+			return err
+		}
+	}
+
+	if options != nil && options.StoreValidatedParams {
+		c.Set(ValidatedParamsKey, validatedParams(req, route, pathParams))
+	}
+	return nil
+}
+
+// validateRepeatedPathParams validates the values of path parameters whose
+// name is repeated in the path template, which the router reports only one
+// value for.
+func validateRepeatedPathParams(ctx context.Context, input *openapi3filter.RequestValidationInput) error {
+	var catchAll []string
+	if input.Route.PathItem != nil {
+		catchAll = catchAllParams(input.Route.PathItem)
+	}
+	repeated := repeatedPathParamValues(input.Request, input.Route.Path, catchAll)
+	for name, values := range repeated {
+		parameter := input.Route.Operation.Parameters.GetByInAndName(openapi3.ParameterInPath, name)
+		if parameter == nil && input.Route.PathItem != nil {
+			parameter = input.Route.PathItem.Parameters.GetByInAndName(openapi3.ParameterInPath, name)
+		}
+		if parameter == nil {
+			continue
+		}
+		for _, value := range values {
+			if value == input.PathParams[name] {
+				continue
+			}
+			occurrence := *input
+			occurrence.PathParams = make(map[string]string, len(input.PathParams))
+			for k, v := range input.PathParams {
+				occurrence.PathParams[k] = v
+			}
+			occurrence.PathParams[name] = value
+			if err := openapi3filter.ValidateParameter(ctx, &occurrence, parameter); err != nil {
+				errorLines := strings.Split(err.Error(), "\n")
+				return &validationError{
+					message: fmt.Sprintf("error in openapi3filter.RequestError: %s", errorLines[0]),
+					err:     err,
+				}
+			}
+		}
+	}
 	return nil
 }
 
+// hasBody reports whether the request may carry a body
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0
+}
+
+// routingRequest returns the request which the router should match against
+// the spec. This is the gin context's request itself, unless options make
+// the router see a different host, in which case a shallow copy is returned
+// so that handlers still see the original request.
+func routingRequest(c *gin.Context, options *Options) *http.Request {
+	req := c.Request
+	if options == nil {
+		return req
+	}
+	var host, proto string
+	if options.HostResolver != nil {
+		host = options.HostResolver(c)
+	}
+	if options.TrustForwardedHost {
+		if host == "" {
+			host = firstHeaderValue(req.Header.Get("X-Forwarded-Host"))
+		}
+		proto = firstHeaderValue(req.Header.Get("X-Forwarded-Proto"))
+	}
+	if host == "" {
+		return req
+	}
+
+	routeReq := *req
+	u := *req.URL
+	routeReq.URL = &u
+	routeReq.Host = host
+	u.Host = host
+	if proto != "" {
+		u.Scheme = proto
+	} else if u.Scheme == "" {
+		u.Scheme = "http"
+		if req.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	return &routeReq
+}
+
+// firstHeaderValue returns the first entry of a comma separated header
+// value, as set by a chain of proxies.
+func firstHeaderValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// canonicalHeader returns a copy of header with its keys in canonical form,
+// and whether any of them, such as ones set directly on the map, wasn't.
+func canonicalHeader(header http.Header) (http.Header, bool) {
+	canonical := true
+	for key := range header {
+		if textproto.CanonicalMIMEHeaderKey(key) != key {
+			canonical = false
+			break
+		}
+	}
+	if canonical {
+		return nil, false
+	}
+	copied := make(http.Header, len(header))
+	for key, values := range header {
+		canonicalKey := textproto.CanonicalMIMEHeaderKey(key)
+		copied[canonicalKey] = append(copied[canonicalKey], values...)
+	}
+	return copied, true
+}
+
+// normalizeContentType lowercases the media type of the Content-Type header,
+// which openapi3filter matches case sensitively against the spec's, although
+// media types are case insensitive. Parameters are left untouched.
+func normalizeContentType(header http.Header) {
+	value := header.Get("Content-Type")
+	mediaType, params, found := strings.Cut(value, ";")
+	if lower := strings.ToLower(mediaType); lower != mediaType {
+		if found {
+			lower += ";" + params
+		}
+		header.Set("Content-Type", lower)
+	}
+}
+
 // GetGinContext gets the gin context from within requests. It returns
 // nil if not found or wrong type.
 func GetGinContext(c context.Context) *gin.Context {
@@ -189,6 +1064,42 @@ func GetUserData(c context.Context) interface{} {
 	return c.Value(UserDataKey)
 }
 
+// GetOperationExtension gets the value of an extension of the operation
+// matched by the middleware, if it was listed in Options.OperationExtensions.
+// It returns nil if the operation doesn't have the extension.
+func GetOperationExtension(c *gin.Context, name string) interface{} {
+	value, _ := c.Get(OperationExtensionKeyPrefix + name)
+	return value
+}
+
+// GetValidationError gets the error behind a failed validation, so that an
+// ErrorHandler can inspect more than its message. It returns nil if
+// validation didn't fail.
+func GetValidationError(c *gin.Context) error {
+	value, _ := c.Get(ValidationErrorKey)
+	err, _ := value.(error)
+	return err
+}
+
+// GetMultiError gets the openapi3.MultiError behind a failed validation, so
+// that an ErrorHandler can render each error itself rather than the message
+// produced by the MultiErrorHandler. It returns nil if the validation didn't
+// fail with a MultiError.
+func GetMultiError(c *gin.Context) openapi3.MultiError {
+	value, _ := c.Get(MultiErrorKey)
+	me, _ := value.(openapi3.MultiError)
+	return me
+}
+
+// GetMatchedRoute returns the route of the spec which the request matched,
+// whether or not it then passed validation. It returns nil if the request
+// didn't match any route, or wasn't seen by the validator.
+func GetMatchedRoute(c *gin.Context) *routers.Route {
+	value, _ := c.Get(MatchedRouteKey)
+	route, _ := value.(*routers.Route)
+	return route
+}
+
 // attempt to get the MultiErrorHandler from the options. If it is not set,
 // return a default handler
 func getMultiErrorHandlerFromOptions(options *Options) MultiErrorHandler {
@@ -207,5 +1118,5 @@ func getMultiErrorHandlerFromOptions(options *Options) MultiErrorHandler {
 // of all of the errors. This method is called if there are no other
 // methods defined on the options.
 func defaultMultiErrorHandler(me openapi3.MultiError) error {
-	return fmt.Errorf("multiple errors encountered: %s", me)
+	return fmt.Errorf("multiple errors encountered: %w", me)
 }
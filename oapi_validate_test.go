@@ -22,13 +22,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,6 +66,29 @@ func doGet(t *testing.T, handler http.Handler, rawURL string) *httptest.Response
 	return tt
 }
 
+func doGetWithHeaders(t *testing.T, handler http.Handler, rawURL string, headers map[string]string) *httptest.ResponseRecorder {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("Invalid url: %s", rawURL)
+	}
+
+	r, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		t.Fatalf("Could not construct a request: %s", rawURL)
+	}
+	r.Header.Set("accept", "application/json")
+	r.Header.Set("host", u.Host)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	tt := httptest.NewRecorder()
+
+	handler.ServeHTTP(tt, r)
+
+	return tt
+}
+
 func doPost(t *testing.T, handler http.Handler, rawURL string, jsonBody interface{}) *httptest.ResponseRecorder {
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -439,3 +471,2788 @@ func TestOapiRequestValidatorWithOptionsMultiErrorAndCustomHandler(t *testing.T)
 		called = false
 	}
 }
+
+func TestOapiRequestValidatorAuthorizationHeaderPattern(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	// No AuthenticationFunc is configured, the Authorization header is only
+	// checked against the pattern of its header parameter.
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+
+	g.GET("/authorization_header_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's send a well-formed bearer token, it should pass
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/authorization_header_resource", map[string]string{
+			"Authorization": "Bearer abc.def-ghi_jkl",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Send a token with the wrong scheme
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/authorization_header_resource", map[string]string{
+			"Authorization": "Basic dXNlcjpwYXNz",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"Authorization\\\" in header")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send a malformed bearer token
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/authorization_header_resource", map[string]string{
+			"Authorization": "Bearer not a token",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Leave out the Authorization header entirely
+	{
+		rec := doGet(t, g, "http://deepmap.ai/authorization_header_resource")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorRouteGroups(t *testing.T) {
+	// Each spec declares its group prefix as a relative server URL, so
+	// that the router matches /v1/... and /v2/... against the spec paths.
+	specV1 := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer v1
+servers:
+  - url: /v1
+paths:
+  /resource:
+    get:
+      operationId: getResourceV1
+      parameters:
+        - name: id
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        '204':
+          description: no content
+`)
+	specV2 := []byte(`
+openapi: "3.0.0"
+info:
+  version: 2.0.0
+  title: TestServer v2
+servers:
+  - url: /v2
+paths:
+  /resource:
+    get:
+      operationId: getResourceV2
+      parameters:
+        - name: id
+          in: query
+          schema:
+            type: string
+            pattern: '^[a-z]+$'
+      responses:
+        '204':
+          description: no content
+`)
+	swaggerV1, err := openapi3.NewLoader().LoadFromData(specV1)
+	require.NoError(t, err, "Error initializing swagger")
+	swaggerV2, err := openapi3.NewLoader().LoadFromData(specV2)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := &Options{SilenceServersWarning: true}
+	v1 := g.Group("/v1")
+	v1.Use(OapiRequestValidatorWithOptions(swaggerV1, options))
+	v1.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	v2 := g.Group("/v2")
+	v2.Use(OapiRequestValidatorWithOptions(swaggerV2, options))
+	v2.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Each group should validate against its own spec
+	{
+		rec := doGet(t, g, "http://deepmap.ai/v1/resource?id=50")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = doGet(t, g, "http://deepmap.ai/v1/resource?id=abc")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+
+	{
+		rec := doGet(t, g, "http://deepmap.ai/v2/resource?id=abc")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = doGet(t, g, "http://deepmap.ai/v2/resource?id=50")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestOapiValidatorFromYamlFileWithBOM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	data := append([]byte{0xEF, 0xBB, 0xBF}, testSchema...)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	validator, err := OapiValidatorFromYamlFile(path)
+	require.NoError(t, err, "BOM-prefixed spec should load")
+
+	g := gin.New()
+	g.Use(validator)
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=50")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOapiRequestValidatorOnRequestValidated(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	var validated []string
+	options := Options{
+		OnRequestValidated: func(c *gin.Context, operationID string, d time.Duration) {
+			assert.NotNil(t, c)
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			validated = append(validated, operationID)
+		},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's send a good request, the hook should fire
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource?id=50")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, []string{"getResource"}, validated)
+	}
+
+	// Send an out-of-spec parameter, the hook should not fire
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, []string{"getResource"}, validated)
+	}
+}
+
+func TestOapiRequestValidatorNullableBody(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.POST("/nullable_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// An explicit null is allowed for a nullable field
+	{
+		rec := doPost(t, g, "http://deepmap.ai/nullable_resource", map[string]interface{}{"field": nil})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// As is a value of the declared type
+	{
+		rec := doPost(t, g, "http://deepmap.ai/nullable_resource", map[string]interface{}{"field": 5})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// But a value of another type is still rejected
+	{
+		rec := doPost(t, g, "http://deepmap.ai/nullable_resource", map[string]interface{}{"field": "five"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorHeaderCase(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	var header http.Header
+	g.GET("/api_key_header_resource", func(c *gin.Context) {
+		called = true
+		header = c.Request.Header
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	doGetWithRawHeader := func(key, value string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, "http://deepmap.ai/api_key_header_resource", nil)
+		require.NoError(t, err)
+		// Bypass Header.Set, which would canonicalize the key for us
+		r.Header[key] = []string{value}
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// A lowercase header name should match the spec's X-Api-Key
+	{
+		rec := doGetWithRawHeader("x-api-key", "0123456789")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+		// without rewriting the handler's header
+		assert.Equal(t, http.Header{"x-api-key": {"0123456789"}}, header)
+	}
+
+	// The handler still gets the body of a request validated through a copy
+	{
+		var body []byte
+		g.POST("/resource", func(c *gin.Context) {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		r, err := http.NewRequest(http.MethodPost, "http://deepmap.ai/resource", strings.NewReader(`{"name": "Rex"}`))
+		require.NoError(t, err)
+		r.Header["content-type"] = []string{"application/json"}
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, r)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `{"name": "Rex"}`, string(body))
+	}
+
+	// And its value should still be validated
+	{
+		rec := doGetWithRawHeader("x-api-key", "short")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorDeepObjectQuery(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.GET("/deep_object_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's send a good deepObject parameter, it should pass
+	{
+		rec := doGet(t, g, "http://deepmap.ai/deep_object_resource?filter[name]=x&filter[age]=30")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Send a property of the wrong type
+	{
+		rec := doGet(t, g, "http://deepmap.ai/deep_object_resource?filter[name]=x&filter[age]=thirty")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"filter\\\" in query")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send a property which violates its schema
+	{
+		rec := doGet(t, g, "http://deepmap.ai/deep_object_resource?filter[age]=-1")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorRouteNotFoundHandler(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		ErrorHandler: func(c *gin.Context, message string, statusCode int) {
+			c.String(statusCode, "test: "+message)
+		},
+		RouteNotFoundHandler: JSONRouteNotFoundHandler,
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.GET("/unmodeled", func(c *gin.Context) {
+		called = true
+	})
+
+	// An unmodeled path goes through the RouteNotFoundHandler
+	{
+		rec := doGet(t, g, "http://deepmap.ai/unmodeled")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+
+		var body RouteNotFoundResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, RouteNotFoundResponse{
+			Status:  http.StatusNotFound,
+			Message: "no matching operation was found",
+			Method:  http.MethodGet,
+			Path:    "/unmodeled",
+		}, body)
+	}
+
+	// Other errors still go through the ErrorHandler
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "test: ")
+	}
+}
+
+func TestOapiRequestValidatorCollectAllErrors(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	// Note that openapi3filter.Options.MultiError is left unset
+	options := Options{
+		CollectAllErrors: true,
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.GET("/multiparamresource", func(c *gin.Context) {
+		called = true
+	})
+
+	// Let's send a good request, it should pass
+	{
+		rec := doGet(t, g, "http://deepmap.ai/multiparamresource?id=50&id2=50")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Let's send a request with a 2 missing parameters, both should be
+	// reported
+	{
+		rec := doGet(t, g, "http://deepmap.ai/multiparamresource")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, "multiple errors encountered")
+		assert.Contains(t, body, "parameter \\\"id\\\"")
+		assert.Contains(t, body, "parameter \\\"id2\\\"")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// The caller's options must not have been modified
+	assert.False(t, options.Options.MultiError)
+}
+
+func TestOapiRequestValidatorRawMultiError(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	var received openapi3.MultiError
+	options := Options{
+		CollectAllErrors: true,
+		ErrorHandler: func(c *gin.Context, message string, statusCode int) {
+			received = GetMultiError(c)
+			c.AbortWithStatusJSON(statusCode, gin.H{"errors": len(received)})
+		},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+	g.GET("/multiparamresource", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/multiparamresource")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"errors":2}`, rec.Body.String())
+	require.Len(t, received, 2)
+	for i, name := range []string{"id", "id2"} {
+		var requestErr *openapi3filter.RequestError
+		require.ErrorAs(t, received[i], &requestErr)
+		assert.Equal(t, name, requestErr.Parameter.Name)
+	}
+}
+
+func TestOapiRequestValidatorCookieSecurity(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	errMissingSession := errors.New("missing session cookie")
+	options := Options{
+		// Security failures are reported as 400 by default, map them to 401
+		ErrorHandler: func(c *gin.Context, message string, statusCode int) {
+			if strings.Contains(message, "SecurityRequirementsError") {
+				statusCode = http.StatusUnauthorized
+			}
+			c.String(statusCode, message)
+		},
+		Options: openapi3filter.Options{
+			AuthenticationFunc: func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+				assert.Equal(t, "CookieAuth", input.SecuritySchemeName)
+				c := GetGinContext(ctx)
+				require.NotNil(t, c)
+				session, err := c.Cookie(input.SecurityScheme.Name)
+				if err != nil || session == "" {
+					return errMissingSession
+				}
+				return nil
+			},
+		},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.GET("/cookie_protected_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Send the session cookie, it should pass
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/cookie_protected_resource", map[string]string{
+			"Cookie": "session=abc123",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Leave out the cookie
+	{
+		rec := doGet(t, g, "http://deepmap.ai/cookie_protected_resource")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), errMissingSession.Error())
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorMaxRequestBodyBytes(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		MaxRequestBodyBytes: 32,
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.POST("/resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// A body within the limit passes
+	{
+		rec := doPost(t, g, "http://deepmap.ai/resource", map[string]string{"name": "Marcin"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// A body over the limit, with its length declared up front
+	{
+		rec := doPost(t, g, "http://deepmap.ai/resource", map[string]string{"name": strings.Repeat("a", 64)})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Contains(t, rec.Body.String(), ErrRequestBodyTooLarge.Error())
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// A body over the limit, of unknown length
+	{
+		body := `{"name": "` + strings.Repeat("a", 64) + `"}`
+		r, err := http.NewRequest(http.MethodPost, "http://deepmap.ai/resource", io.NopCloser(strings.NewReader(body)))
+		require.NoError(t, err)
+		r.Header.Set("content-type", "application/json")
+		require.EqualValues(t, 0, r.ContentLength)
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, r)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorTrustForwardedHost(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		TrustForwardedHost: true,
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	var host string
+	called := false
+	g.GET("/resource", func(c *gin.Context) {
+		called = true
+		host = c.Request.Host
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The internal host doesn't match the spec's server, but the forwarded
+	// one does
+	{
+		rec := doGetWithHeaders(t, g, "http://internal.local/resource", map[string]string{
+			"X-Forwarded-Host":  "deepmap.ai",
+			"X-Forwarded-Proto": "http",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		assert.Equal(t, "internal.local", host, "Handler should see the original host")
+		called = false
+	}
+
+	// A forwarded scheme which the spec's server doesn't use
+	{
+		rec := doGetWithHeaders(t, g, "http://internal.local/resource", map[string]string{
+			"X-Forwarded-Host":  "deepmap.ai",
+			"X-Forwarded-Proto": "https",
+		})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Without forwarded headers, the internal host is used
+	{
+		rec := doGet(t, g, "http://internal.local/resource")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Without the option, forwarded headers are ignored
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidator(swagger))
+		rec := doGetWithHeaders(t, g, "http://internal.local/resource", map[string]string{
+			"X-Forwarded-Host": "deepmap.ai",
+		})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestOapiRequestValidatorStatusCodeResolver(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	errConflict := errors.New("conflicting session")
+	options := Options{
+		Options: openapi3filter.Options{
+			AuthenticationFunc: func(c context.Context, input *openapi3filter.AuthenticationInput) error {
+				return errConflict
+			},
+		},
+		StatusCodeResolver: func(err error) int {
+			if errors.Is(err, errConflict) {
+				return http.StatusConflict
+			}
+			return 0
+		},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.GET("/protected_resource", func(c *gin.Context) {
+		called = true
+	})
+	g.GET("/resource", func(c *gin.Context) {
+		called = true
+	})
+
+	// The resolver maps this error to a 409
+	{
+		rec := doGet(t, g, "http://deepmap.ai/protected_resource")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), errConflict.Error())
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Other errors keep their default status
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+
+		rec = doGet(t, g, "http://deepmap.ai/unmodeled")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestOapiRequestValidatorOneOfBody(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.POST("/composite_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// A body matching exactly one branch passes
+	{
+		rec := doPost(t, g, "http://deepmap.ai/composite_resource", map[string]interface{}{"meow": true})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+
+		rec = doPost(t, g, "http://deepmap.ai/composite_resource", map[string]interface{}{"bark": true})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// A body matching none of the branches fails
+	{
+		rec := doPost(t, g, "http://deepmap.ai/composite_resource", map[string]interface{}{"meow": "loudly"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error: doesn't match schema")
+		assert.NotContains(t, rec.Body.String(), "multiple errors encountered")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// A body matching several branches fails too
+	{
+		rec := doPost(t, g, "http://deepmap.ai/composite_resource", map[string]interface{}{"meow": true, "bark": true})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "input matches more than one oneOf schemas")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorErrorPointerHeader(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		ErrorPointerHeader: "X-Error-Pointer",
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	g.POST("/nested_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.GET("/multiparamresource", func(c *gin.Context) {})
+
+	// A nested field error reports its pointer
+	{
+		body := map[string]interface{}{
+			"items": []map[string]interface{}{{"id": 1}, {"id": 2}, {"id": 0}},
+		}
+		rec := doPost(t, g, "http://deepmap.ai/nested_resource", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "/items/2/id", rec.Header().Get("X-Error-Pointer"))
+	}
+
+	// Errors which aren't about a value in the body don't set the header
+	{
+		rec := doGet(t, g, "http://deepmap.ai/multiparamresource")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Error-Pointer"))
+	}
+
+	// Neither do valid requests
+	{
+		body := map[string]interface{}{
+			"items": []map[string]interface{}{{"id": 1}},
+		}
+		rec := doPost(t, g, "http://deepmap.ai/nested_resource", body)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("X-Error-Pointer"))
+	}
+}
+
+func TestOapiRequestValidatorFromURL(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first request, as a config server which is restarting
+		// might
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(testSchema)
+	}))
+	defer server.Close()
+
+	validator, err := OapiRequestValidatorFromURL(context.Background(), server.URL+"/openapi.yaml", 3, time.Millisecond)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, requests.Load())
+
+	g := gin.New()
+	g.Use(validator)
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=50")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOapiRequestValidatorFromURLGivesUp(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/missing.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Transient failures are retried as many times as allowed
+	{
+		requests.Store(0)
+		_, err := OapiRequestValidatorFromURL(context.Background(), server.URL+"/openapi.yaml", 3, time.Millisecond)
+		assert.Error(t, err)
+		assert.EqualValues(t, 3, requests.Load())
+	}
+
+	// Other failures aren't retried
+	{
+		requests.Store(0)
+		_, err := OapiRequestValidatorFromURL(context.Background(), server.URL+"/missing.yaml", 3, time.Millisecond)
+		assert.Error(t, err)
+		assert.EqualValues(t, 1, requests.Load())
+	}
+
+	// Retries stop when the context's deadline doesn't leave room for them
+	{
+		requests.Store(0)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := OapiRequestValidatorFromURL(ctx, server.URL+"/openapi.yaml", 5, time.Second)
+		assert.Error(t, err)
+		assert.EqualValues(t, 1, requests.Load())
+	}
+}
+
+func TestOapiRequestValidatorOperationExtensions(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		OperationExtensions: []string{"x-gin-handler"},
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	var handlerName interface{}
+	g.POST("/resource", func(c *gin.Context) {
+		handlerName = GetOperationExtension(c, "x-gin-handler")
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.GET("/resource", func(c *gin.Context) {
+		handlerName = GetOperationExtension(c, "x-gin-handler")
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The matched operation declares the extension
+	{
+		rec := doPost(t, g, "http://deepmap.ai/resource", map[string]string{"name": "Marcin"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "CreateResource", handlerName)
+	}
+
+	// This one doesn't
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Nil(t, handlerName)
+	}
+}
+
+func TestOapiValidatorFromYamlDataWithBase(t *testing.T) {
+	spec := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /users:
+    post:
+      operationId: createUser
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: './schemas/user.yaml'
+      responses:
+        '204':
+          description: no content
+`)
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "schemas"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "schemas", "user.yaml"), []byte(`
+type: object
+required:
+  - name
+properties:
+  name:
+    type: string
+`), 0o600))
+
+	validator, err := OapiValidatorFromYamlDataWithBase(spec, root)
+	require.NoError(t, err)
+
+	g := gin.New()
+	g.Use(validator)
+	g.POST("/users", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The referenced schema is enforced
+	rec := doPost(t, g, "http://deepmap.ai/users", map[string]interface{}{"name": "Wilma"})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doPost(t, g, "http://deepmap.ai/users", map[string]interface{}{"nickname": "Wilma"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "doesn't match schema ./schemas/user.yaml")
+
+	// Another root doesn't have the schema
+	_, err = OapiValidatorFromYamlDataWithBase(spec, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestOapiValidatorFromYamlFileWithLoader(t *testing.T) {
+	spec := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: 'https://schemas.example.com/pet.yaml#/Pet'
+      responses:
+        '204':
+          description: no content
+`)
+	mirror := map[string][]byte{
+		"https://schemas.example.com/pet.yaml": []byte(`
+Pet:
+  type: object
+  required:
+    - name
+  properties:
+    name:
+      type: string
+`),
+	}
+
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	require.NoError(t, os.WriteFile(path, spec, 0o600))
+
+	// The default loader refuses external references
+	_, err := OapiValidatorFromYamlFile(path)
+	assert.Error(t, err)
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, u *url.URL) ([]byte, error) {
+		data, ok := mirror[u.String()]
+		if !ok {
+			return nil, fmt.Errorf("%s is not mirrored", u)
+		}
+		return data, nil
+	}
+	validator, err := OapiValidatorFromYamlFileWithLoader(path, loader)
+	require.NoError(t, err)
+
+	g := gin.New()
+	g.Use(validator)
+	g.POST("/pets", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The schema from the mirror should be enforced
+	rec := doPost(t, g, "http://deepmap.ai/pets", map[string]interface{}{"name": "Rex"})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doPost(t, g, "http://deepmap.ai/pets", map[string]interface{}{"age": 3})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOapiRequestValidatorMissingContentType(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	doPostWithoutContentType := func(g *gin.Engine, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodPost, "http://deepmap.ai/resource", strings.NewReader(body))
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// Without a default, the request is rejected with a clear message
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidator(swagger))
+		g.POST("/resource", func(c *gin.Context) {
+			t.Error("Handler should not have been called")
+		})
+
+		rec := doPostWithoutContentType(g, `{"name": "Marcin"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "missing Content-Type header")
+	}
+
+	// With a default, the body is validated as that content type
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+			DefaultRequestContentType: "application/json",
+		}))
+		var contentType string
+		g.POST("/resource", func(c *gin.Context) {
+			contentType = c.ContentType()
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+
+		rec := doPostWithoutContentType(g, `{"name": "Marcin"}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "application/json", contentType)
+
+		rec = doPostWithoutContentType(g, `{"name": 7}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestOapiRequestValidatorRepeatedPathParam(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	// gin doesn't allow repeating a wildcard name, so use distinct ones
+	g.GET("/repeated/:id/nested/:id2", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Both occurrences are valid
+	{
+		rec := doGet(t, g, "http://deepmap.ai/repeated/1/nested/2")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Either occurrence being invalid fails the request
+	for _, path := range []string{"/repeated/0/nested/2", "/repeated/1/nested/0", "/repeated/abc/nested/2"} {
+		rec := doGet(t, g, "http://deepmap.ai"+path)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"id\\\" in path", path)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorRepeatedCatchAllPathParam(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /copy/{path}/to/{path}:
+    get:
+      operationId: copyFile
+      parameters:
+        - name: path
+          in: path
+          required: true
+          x-catch-all: true
+          schema:
+            type: string
+            pattern: '^[a-z]+(/[a-z]+)*$'
+      responses:
+        '204':
+          description: no content
+`))
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.GET("/copy/*path", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Both occurrences are valid
+	{
+		rec := doGet(t, g, "http://deepmap.ai/copy/a/b/to/c/d")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Either occurrence being invalid fails the request, though it spans
+	// several segments
+	for _, path := range []string{"/copy/a/B/to/c/d", "/copy/a/b/to/c/D"} {
+		rec := doGet(t, g, "http://deepmap.ai"+path)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"path\\\" in path", path)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorMutuallyExclusiveParams(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		EnforceMutuallyExclusiveParams: true,
+	}))
+
+	called := false
+	g.GET("/mutually_exclusive_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// One of the parameters is fine
+	for _, query := range []string{"", "?after=a", "?before=b"} {
+		rec := doGet(t, g, "http://deepmap.ai/mutually_exclusive_resource"+query)
+		assert.Equal(t, http.StatusNoContent, rec.Code, query)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Both of them are rejected
+	{
+		rec := doGet(t, g, "http://deepmap.ai/mutually_exclusive_resource?after=a&before=b")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `query parameters \"after\", \"before\" are mutually exclusive`)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// The extension is ignored unless the option is set
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidator(swagger))
+		g.GET("/mutually_exclusive_resource", func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		rec := doGet(t, g, "http://deepmap.ai/mutually_exclusive_resource?after=a&before=b")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	// A malformed extension is reported when the validator is created
+	{
+		swagger, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /items:
+    get:
+      operationId: getItems
+      x-mutually-exclusive: after
+      responses:
+        '204':
+          description: no content
+`))
+		require.NoError(t, err, "Error initializing swagger")
+
+		_, err = NewOapiRequestValidator(swagger, &Options{EnforceMutuallyExclusiveParams: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error in operation GET /items: x-mutually-exclusive must be a list")
+
+		_, err = NewOapiRequestValidator(swagger, nil)
+		assert.NoError(t, err)
+	}
+}
+
+func TestOapiRequestValidatorEnumHeader(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.GET("/versioned_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// An allowed version passes
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/versioned_resource", map[string]string{"X-Api-Version": "2024-06-01"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Any other version is rejected, listing the allowed ones
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/versioned_resource", map[string]string{"X-Api-Version": "2022-01-01"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, "parameter \\\"X-Api-Version\\\" in header has an error")
+		assert.Contains(t, body, "value is not one of the allowed values")
+		assert.Contains(t, body, "2023-01-01")
+		assert.Contains(t, body, "2024-06-01")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorSkipSecurityValidation(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		Options: openapi3filter.Options{
+			AuthenticationFunc: func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+				t.Error("AuthenticationFunc should not have been called")
+				return errors.New("unauthorized")
+			},
+		},
+		SkipSecurityValidation: true,
+	}))
+
+	called := false
+	g.POST("/api_key_protected_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// No credentials are needed for a valid body
+	{
+		rec := doPost(t, g, "http://deepmap.ai/api_key_protected_resource", gin.H{"name": "Wilma"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// But the body is still validated
+	{
+		rec := doPost(t, g, "http://deepmap.ai/api_key_protected_resource", gin.H{"nickname": "Wilma"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorOptionalSecurity(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		Options: openapi3filter.Options{
+			AuthenticationFunc: func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+				if input.RequestValidationInput.Request.Header.Get("X-Api-Key") != "secret-key" {
+					return errors.New("invalid API key")
+				}
+				return nil
+			},
+		},
+	}))
+
+	called := false
+	g.GET("/optional_auth_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The empty requirement lets requests without credentials through
+	{
+		rec := doGet(t, g, "http://deepmap.ai/optional_auth_resource")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// As well as authenticated ones
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/optional_auth_resource", map[string]string{"X-Api-Key": "secret-key"})
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Invalid credentials fall back to the empty requirement too
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/optional_auth_resource", map[string]string{"X-Api-Key": "wrong"})
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+	}
+}
+
+func TestOapiRequestValidatorRejectUnknownHeaders(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		RejectUnknownHeaders: true,
+		AllowedHeaders:       []string{"x-request-id", "x-amzn-*"},
+	}))
+
+	called := false
+	g.GET("/versioned_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Declared, standard and allowed headers pass
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/versioned_resource", map[string]string{
+			"X-Api-Version": "2024-06-01",
+			"Accept":        "application/json",
+			"User-Agent":    "test",
+			"X-Request-Id":  "abc",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// So do the headers browsers and proxies routinely send, and those
+	// matching an allowed prefix
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/versioned_resource", map[string]string{
+			"X-Api-Version":             "2024-06-01",
+			"Sec-Fetch-Mode":            "navigate",
+			"Sec-Ch-Ua-Platform":        `"Linux"`,
+			"Dnt":                       "1",
+			"Upgrade-Insecure-Requests": "1",
+			"Traceparent":               "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			"X-Real-Ip":                 "10.0.0.1",
+			"X-Amzn-Trace-Id":           "Root=1-5759e988-bd862e3fe1be46a994272793",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// An undeclared custom header is rejected
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/versioned_resource", map[string]string{
+			"X-Api-Version": "2024-06-01",
+			"X-Debug":       "1",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `header \"X-Debug\" is not allowed`)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorMultipart(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.POST("/multipart_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	doMultipart := func(metadata string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="metadata"`)
+		h.Set("Content-Type", "application/json")
+		part, err := w.CreatePart(h)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(metadata))
+		require.NoError(t, err)
+
+		h = make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="file"; filename="data.bin"`)
+		h.Set("Content-Type", "application/octet-stream")
+		part, err = w.CreatePart(h)
+		require.NoError(t, err)
+		_, err = part.Write([]byte{0x00, 0x01, 0x02})
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/multipart_resource", &body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A JSON part matching its schema, along with a file, passes
+	{
+		rec := doMultipart(`{"name": "Wilma", "size": 3}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// A JSON part not matching its schema is rejected
+	{
+		rec := doMultipart(`{"size": "three"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// So is a malformed JSON part
+	{
+		rec := doMultipart(`{"name": "Wilma"`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorRecordErrorInContext(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	var recorded []*gin.Error
+	// A logging middleware registered before the validator sees the error
+	g.Use(func(c *gin.Context) {
+		c.Next()
+		recorded = c.Errors
+	})
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		RecordErrorInContext: true,
+	}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "parameter \\\"id\\\" in query has an error")
+
+	require.Len(t, recorded, 1)
+	assert.Equal(t, gin.ErrorTypePrivate, recorded[0].Type)
+	var requestErr *openapi3filter.RequestError
+	assert.ErrorAs(t, recorded[0].Err, &requestErr)
+}
+
+func TestOapiRequestValidatorValidationExtension(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		UseValidationExtension: true,
+	}))
+	g.GET("/overridden_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.POST("/overridden_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The GET operation opts out of request validation
+	{
+		rec := doGet(t, g, "http://deepmap.ai/overridden_resource?id=abc")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	// The POST operation only opts out of body validation
+	{
+		rec := doPost(t, g, "http://deepmap.ai/overridden_resource?id=1", gin.H{"nickname": "Wilma"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = doPost(t, g, "http://deepmap.ai/overridden_resource?id=abc", gin.H{"name": "Wilma"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"id\\\" in query has an error")
+	}
+
+	// Without the option, the extension is ignored
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidator(swagger))
+		g.GET("/overridden_resource", func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		rec := doGet(t, g, "http://deepmap.ai/overridden_resource?id=abc")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+
+	// A malformed extension is reported when the validator is created,
+	// rather than to every client
+	{
+		swagger, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /items:
+    get:
+      operationId: getItems
+      x-validation:
+        skipRequest: sometimes
+      responses:
+        '204':
+          description: no content
+`))
+		require.NoError(t, err, "Error initializing swagger")
+
+		_, err = NewOapiRequestValidator(swagger, &Options{UseValidationExtension: true})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error in operation GET /items: error reading x-validation")
+
+		_, err = NewOapiRequestValidator(swagger, nil)
+		assert.NoError(t, err)
+	}
+}
+
+func TestOapiRequestValidatorParameterizedContentType(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.POST("/resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	doPostContentType := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/resource", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, contentType := range []string{"application/json; charset=utf-8", "Application/JSON;charset=UTF-8"} {
+		// The spec's application/json entry matches, and its schema applies
+		rec := doPostContentType(contentType, `{"name": "Wilma"}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code, contentType)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+
+		rec = doPostContentType(contentType, `{"name": 7}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, contentType)
+		assert.Contains(t, rec.Body.String(), "request body has an error: doesn't match schema", contentType)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorByteAndBinaryBodies(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	var received []byte
+	g.POST("/encoded_resource", func(c *gin.Context) {
+		var err error
+		received, err = io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	doPostBody := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/encoded_resource", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A base64 body passes format: byte
+	{
+		rec := doPostBody("text/plain", []byte("aGVsbG8gd29ybGQ="))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "aGVsbG8gd29ybGQ=", string(received))
+		received = nil
+	}
+
+	// Anything else fails it
+	{
+		rec := doPostBody("text/plain", []byte("hello world!"))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error")
+		assert.Nil(t, received, "Handler should not have been called")
+	}
+
+	// A format: binary body isn't parsed as JSON, and reaches the handler
+	// unchanged
+	{
+		body := []byte{'{', 0xff, 0x00, 0xfe}
+		rec := doPostBody("application/octet-stream", body)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, body, received)
+	}
+}
+
+func TestOapiRequestValidatorContextFunc(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	type tenantKey struct{}
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		ContextFunc: func(c *gin.Context) context.Context {
+			return context.WithValue(c.Request.Context(), tenantKey{}, c.GetHeader("X-Tenant"))
+		},
+		UserData: "hi!",
+		Options: openapi3filter.Options{
+			AuthenticationFunc: func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+				// The custom value is there, alongside the middleware's own
+				assert.NotNil(t, GetGinContext(ctx))
+				assert.Equal(t, "hi!", GetUserData(ctx))
+				if ctx.Value(tenantKey{}) != "acme" {
+					return errors.New("unknown tenant")
+				}
+				return nil
+			},
+		},
+	}))
+
+	called := false
+	g.GET("/api_key_protected_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/api_key_protected_resource", map[string]string{"X-Tenant": "acme"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/api_key_protected_resource", map[string]string{"X-Tenant": "other"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "unknown tenant")
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// A nil context falls back on the request's
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+			ContextFunc: func(c *gin.Context) context.Context {
+				return nil
+			},
+			Options: openapi3filter.Options{
+				AuthenticationFunc: func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+					assert.NotNil(t, GetGinContext(ctx))
+					return nil
+				},
+			},
+		}))
+		g.GET("/api_key_protected_resource", func(c *gin.Context) {
+			called = true
+			c.AbortWithStatus(http.StatusNoContent)
+		})
+		rec := doGet(t, g, "http://deepmap.ai/api_key_protected_resource")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+	}
+}
+
+func TestOapiRequestValidatorCatchAllPathParam(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		OnRequestValidated: func(c *gin.Context, operationID string, d time.Duration) {
+			assert.Equal(t, "getFile", operationID)
+		},
+	}))
+
+	called := false
+	g.GET("/files/*path", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The whole of a/b/c is the path parameter
+	{
+		rec := doGet(t, g, "http://deepmap.ai/files/a/b/c")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// and is validated as such
+	{
+		rec := doGet(t, g, "http://deepmap.ai/files/a/B/c")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"path\\\" in path has an error")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorCatchAllSiblingRoute(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /files/{path}:
+    get:
+      operationId: getFile
+      parameters:
+        - name: path
+          in: path
+          required: true
+          x-catch-all: true
+          schema:
+            type: string
+      responses:
+        '204':
+          description: no content
+  /files/{id}/meta:
+    get:
+      operationId: getMeta
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+      responses:
+        '204':
+          description: no content
+`))
+	require.NoError(t, err, "Error initializing swagger")
+
+	var operationID string
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		OnRequestValidated: func(c *gin.Context, id string, d time.Duration) {
+			operationID = id
+		},
+	}))
+	g.GET("/files/*path", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The more specific route keeps its requests
+	{
+		rec := doGet(t, g, "http://deepmap.ai/files/12/meta")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, "getMeta", operationID)
+		operationID = ""
+	}
+
+	// and validates them, rather than the catch-all route letting them by
+	{
+		rec := doGet(t, g, "http://deepmap.ai/files/abc/meta")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"id\\\" in path has an error")
+		assert.Empty(t, operationID)
+	}
+
+	// Other requests fall back on the catch-all route
+	{
+		rec := doGet(t, g, "http://deepmap.ai/files/a/b/meta")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, "getFile", operationID)
+		operationID = ""
+
+		rec = doGet(t, g, "http://deepmap.ai/files/abc")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, "getFile", operationID)
+	}
+}
+
+func TestOapiRequestValidatorValidateOncePerRoute(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	validated := 0
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		ValidateOncePerRoute: true,
+		OnRequestValidated: func(c *gin.Context, operationID string, d time.Duration) {
+			validated++
+		},
+		OperationExtensions: []string{"x-gin-handler"},
+	}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	var handlers []interface{}
+	g.POST("/resource", func(c *gin.Context) {
+		handlers = append(handlers, GetOperationExtension(c, "x-gin-handler"))
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.GET("/multiparamresource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Requests to an operation are validated until one passes
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=50")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Later ones aren't, even when invalid
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doGet(t, g, "http://deepmap.ai/resource")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Other operations are tracked separately, even when they share an
+	// operationId
+	rec = doGet(t, g, "http://deepmap.ai/multiparamresource")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = doGet(t, g, "http://deepmap.ai/multiparamresource?id=50&id2=50")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doGet(t, g, "http://deepmap.ai/multiparamresource")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Operation extensions are stored for requests which aren't validated
+	// too
+	for i := 0; i < 2; i++ {
+		rec = doPost(t, g, "http://deepmap.ai/resource", gin.H{"name": "Wilma"})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+	assert.Equal(t, []interface{}{"CreateResource", "CreateResource"}, handlers)
+
+	assert.Equal(t, 3, validated)
+}
+
+func TestOapiRequestValidatorSpecPath(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		SpecPath: "/openapi.yaml",
+	}))
+	g.GET("/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", testSchema)
+	})
+	g.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, swagger)
+	})
+
+	// The spec endpoint passes through
+	rec := doGet(t, g, "http://deepmap.ai/openapi.yaml")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, testSchema, rec.Body.Bytes())
+
+	// Other unmodeled paths are still rejected
+	rec = doGet(t, g, "http://deepmap.ai/openapi.json")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestOapiRequestValidatorAllowReserved(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	var redirect string
+	g.GET("/reserved_resource", func(c *gin.Context) {
+		redirect = c.Query("redirect")
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Reserved characters are sent unencoded
+	{
+		rec := doGet(t, g, "http://deepmap.ai/reserved_resource?redirect=/login:admin@host/next?step=2")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, "/login:admin@host/next?step=2", redirect)
+	}
+
+	// and the value is still validated
+	{
+		rec := doGet(t, g, "http://deepmap.ai/reserved_resource?redirect=login:admin@host")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"redirect\\\" in query has an error")
+	}
+}
+
+func TestOapiRequestValidatorPassThroughUnmatched(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		PassThroughUnmatched: true,
+	}))
+	g.GET("/healthz", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	g.DELETE("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// An unmodeled path reaches its gin handler
+	rec := doGet(t, g, "http://deepmap.ai/healthz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+
+	// So does an unmodeled method of a modeled path
+	req := httptest.NewRequest(http.MethodDelete, "http://deepmap.ai/resource", nil)
+	rec = httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// Matched operations are still validated
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOapiRequestValidatorErrorJSONKey(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		ErrorJSONKey: "message",
+	}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body, 1)
+	assert.Contains(t, body["message"], "parameter \"id\" in query has an error")
+}
+
+func TestOapiRequestValidatorErrorTemplate(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	tmpl := template.Must(template.New("error").Funcs(ErrorTemplateFuncs).Parse(
+		`{"code": {{.Status}}, "operation": {{json .OperationID}}, "detail": {{json .Error}}}`))
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		ErrorTemplate: tmpl,
+	}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	var body struct {
+		Code      int    `json:"code"`
+		Operation string `json:"operation"`
+		Detail    string `json:"detail"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusBadRequest, body.Code)
+	assert.Equal(t, "getResource", body.Operation)
+	assert.Contains(t, body.Detail, "parameter \"id\" in query has an error")
+
+	// Unmatched routes have no operation
+	rec = doGet(t, g, "http://deepmap.ai/unknown")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusNotFound, body.Code)
+	assert.Empty(t, body.Operation)
+}
+
+func TestOapiRequestValidatorMaxJSONDepth(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		MaxJSONDepth: 4,
+	}))
+	var received map[string]interface{}
+	g.POST("/nested_resource", func(c *gin.Context) {
+		received = nil
+		require.NoError(t, c.ShouldBindJSON(&received))
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/nested_resource", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A shallow body is validated as usual, and is still readable
+	{
+		rec := post(`{"items": [{"id": 1}]}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": float64(1)}}}, received)
+	}
+
+	// A deeply nested one is rejected before schema validation
+	{
+		received = nil
+		body := `{"items": [{"id": 1, "extra": ` + strings.Repeat("[", 10000) + strings.Repeat("]", 10000) + `}]}`
+		rec := post(body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "maximum JSON nesting depth is 4")
+		assert.Nil(t, received)
+	}
+}
+
+func TestOapiRequestValidatorSkipper(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		Skipper: func(c *gin.Context) bool {
+			return c.Request.URL.Path == "/healthz" || c.Request.URL.Path == "/resource"
+		},
+	}))
+
+	called := false
+	g.GET("/healthz", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.POST("/resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.GET("/multiparamresource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// A skipped path that isn't in the spec reaches the handler
+	{
+		rec := doGet(t, g, "http://deepmap.ai/healthz")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// So does an invalid body on a skipped path
+	{
+		rec := doPost(t, g, "http://deepmap.ai/resource", gin.H{"name": 7})
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Other paths are still validated
+	{
+		rec := doGet(t, g, "http://deepmap.ai/multiparamresource?id=50")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorAllOfParameter(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+	called := false
+	g.GET("/composed_parameter_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// A value satisfying every schema of the allOf passes
+	{
+		rec := doGet(t, g, "http://deepmap.ai/composed_parameter_resource?limit=20")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Violating either of them is rejected
+	for limit, message := range map[string]string{
+		"0":   "number must be at least 1",
+		"25":  "number must be a multiple of 10",
+		"abc": "an invalid integer",
+	} {
+		rec := doGet(t, g, "http://deepmap.ai/composed_parameter_resource?limit="+limit)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, limit)
+		assert.Contains(t, rec.Body.String(), message, limit)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorStoreValidatedParams(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		StoreValidatedParams: true,
+	}))
+	var params map[string]interface{}
+	handler := func(c *gin.Context) {
+		params = GetValidatedParams(c)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+	g.GET("/resource", handler)
+	g.GET("/composed_parameter_resource", handler)
+	g.GET("/content_parameter_resource", handler)
+
+	// Query integers are ints
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource?id=50")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, map[string]interface{}{"id": 50}, params)
+		limit, ok := params["id"].(int)
+		assert.True(t, ok)
+		assert.Equal(t, 50, limit)
+	}
+
+	// Absent parameters are left out
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Empty(t, params)
+	}
+
+	// Composed schemas aren't typed
+	{
+		rec := doGet(t, g, "http://deepmap.ai/composed_parameter_resource?limit=20")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, map[string]interface{}{"limit": "20"}, params)
+	}
+
+	// JSON content is decoded
+	{
+		filter := url.QueryEscape(`{"name": "Rex", "limit": 10}`)
+		rec := doGet(t, g, "http://deepmap.ai/content_parameter_resource?filter="+filter)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, map[string]interface{}{
+			"filter": map[string]interface{}{"name": "Rex", "limit": float64(10)},
+		}, params)
+	}
+}
+
+func TestOapiRequestValidatorStoreValidatedParamsStyles(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		StoreValidatedParams: true,
+	}))
+	var params map[string]interface{}
+	var byLocation map[string]map[string]interface{}
+	handler := func(c *gin.Context) {
+		params = GetValidatedParams(c)
+		byLocation = map[string]map[string]interface{}{}
+		for _, in := range []string{openapi3.ParameterInPath, openapi3.ParameterInQuery, openapi3.ParameterInHeader} {
+			if p := GetValidatedParamsIn(c, in); p != nil {
+				byLocation[in] = p
+			}
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+	g.GET("/deep_object_resource", handler)
+	g.GET("/styled_parameter_resource/:ids/:point", handler)
+
+	// deepObject properties are gathered into an object
+	{
+		rec := doGet(t, g, "http://deepmap.ai/deep_object_resource?filter[name]=Rex&filter[age]=3")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, map[string]interface{}{
+			"filter": map[string]interface{}{"name": "Rex", "age": 3},
+		}, params)
+	}
+
+	// Label and matrix path parameters lose their prefix, exploded form
+	// objects are gathered, and a header doesn't clash with the query
+	// parameter of the same name
+	{
+		rec := doGetWithHeaders(t, g, "http://deepmap.ai/styled_parameter_resource/.1,2,3/;x=4;y=5?lat=1.5&lon=-2&id=6", map[string]string{
+			"Id": "seven",
+		})
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Equal(t, map[string]map[string]interface{}{
+			"path": {
+				"ids":   []interface{}{1, 2, 3},
+				"point": map[string]interface{}{"x": 4, "y": 5},
+			},
+			"query": {
+				"coords": map[string]interface{}{"lat": 1.5, "lon": float64(-2)},
+				"id":     6,
+			},
+			"header": {"id": "seven"},
+		}, byLocation)
+		// The flat map keeps the query parameter
+		assert.Equal(t, 6, params["id"])
+	}
+}
+
+func TestOapiRequestValidatorProblemJSON(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		ProblemJSON: true,
+	}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	var problem map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Len(t, problem, 4)
+	assert.Equal(t, "about:blank", problem["type"])
+	assert.Equal(t, "Bad Request", problem["title"])
+	assert.Equal(t, float64(http.StatusBadRequest), problem["status"])
+	assert.Contains(t, problem["detail"], "parameter \"id\" in query has an error")
+}
+
+func TestGetMatchedRoute(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	var route *routers.Route
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		PassThroughUnmatched: true,
+	}))
+	handler := func(c *gin.Context) {
+		route = GetMatchedRoute(c)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+	g.GET("/resource", handler)
+	g.GET("/unknown", handler)
+
+	// The handler sees the operation its request matched
+	{
+		rec := doGet(t, g, "http://deepmap.ai/resource?id=50")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		require.NotNil(t, route)
+		assert.Equal(t, "getResource", route.Operation.OperationID)
+		assert.Equal(t, "/resource", route.Path)
+	}
+
+	// There's no route for requests which didn't match one
+	{
+		rec := doGet(t, g, "http://deepmap.ai/unknown")
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.Nil(t, route)
+	}
+}
+
+func TestOapiRequestValidatorDisableContextInjection(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	authenticated := false
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		DisableContextInjection: true,
+		UserData:                "hi!",
+		Options: openapi3filter.Options{
+			AuthenticationFunc: func(ctx context.Context, input *openapi3filter.AuthenticationInput) error {
+				authenticated = true
+				assert.Nil(t, GetGinContext(ctx))
+				assert.Nil(t, GetUserData(ctx))
+				return nil
+			},
+		},
+	}))
+	g.GET("/protected_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/protected_resource")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	assert.True(t, authenticated, "AuthenticationFunc should have been called")
+}
+
+func TestNewOapiRequestValidator(t *testing.T) {
+	// The router can't be built for a path template with unbalanced braces
+	swagger, err := openapi3.NewLoader().LoadFromData([]byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /items/{id:
+    get:
+      operationId: getItem
+      responses:
+        '204':
+          description: no content
+`))
+	require.NoError(t, err, "Error initializing swagger")
+
+	validator, err := NewOapiRequestValidator(swagger, nil)
+	assert.Nil(t, validator)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unbalanced braces")
+
+	// The existing constructor still panics
+	assert.Panics(t, func() {
+		OapiRequestValidatorWithOptions(swagger, nil)
+	})
+
+	// A good spec gives a working validator
+	swagger, err = openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+	validator, err = NewOapiRequestValidator(swagger, nil)
+	require.NoError(t, err)
+
+	g := gin.New()
+	g.Use(validator)
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	assert.Equal(t, http.StatusNoContent, doGet(t, g, "http://deepmap.ai/resource?id=50").Code)
+	assert.Equal(t, http.StatusBadRequest, doGet(t, g, "http://deepmap.ai/resource?id=500").Code)
+}
+
+func TestOapiRequestValidatorPathItemParameters(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+	g.GET("/shared_parameter_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	g.DELETE("/shared_parameter_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The path item's header parameter applies to every method
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		do := func(source string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, "http://deepmap.ai/shared_parameter_resource", nil)
+			if source != "" {
+				req.Header.Set("X-Request-Source", source)
+			}
+			rec := httptest.NewRecorder()
+			g.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := do("web")
+		assert.Equal(t, http.StatusNoContent, rec.Code, method)
+
+		rec = do("")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, method)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"X-Request-Source\\\" in header has an error: value is required but missing", method)
+
+		rec = do("desktop")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, method)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"X-Request-Source\\\" in header has an error", method)
+	}
+}
+
+func TestOapiRequestValidatorTopLevelArrayBody(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	var received []map[string]interface{}
+	g.POST("/bulk_resource", func(c *gin.Context) {
+		received = nil
+		require.NoError(t, c.ShouldBindJSON(&received))
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// A valid array reaches the handler intact
+	{
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", []gin.H{{"id": 1, "name": "a"}, {"name": "b"}})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Len(t, received, 2)
+	}
+
+	// An element of the wrong type is rejected
+	{
+		received = nil
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", []interface{}{gin.H{"name": "a"}, "b"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error: doesn't match schema")
+		assert.Nil(t, received, "Handler should not have been called")
+	}
+
+	// So is an object where an array is expected
+	{
+		rec := doPost(t, g, "http://deepmap.ai/bulk_resource", gin.H{"name": "a"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "request body has an error: doesn't match schema")
+	}
+}
+
+func TestOapiRequestValidatorSetEnabled(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+	t.Cleanup(func() { SetEnabled(true) })
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	assert.True(t, Enabled())
+	rec := doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	SetEnabled(false)
+	assert.False(t, Enabled())
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doGet(t, g, "http://deepmap.ai/unmodeled")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "gin itself should not find the route")
+
+	SetEnabled(true)
+	rec = doGet(t, g, "http://deepmap.ai/resource?id=500")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOapiRequestValidatorReadOnlyWriteOnly(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.POST("/account_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// writeOnly fields belong in requests
+	{
+		rec := doPost(t, g, "http://deepmap.ai/account_resource", gin.H{"name": "Wilma", "password": "secret"})
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// readOnly fields don't
+	{
+		rec := doPost(t, g, "http://deepmap.ai/account_resource", gin.H{"id": 1, "name": "Wilma"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "readOnly property \\\"id\\\" in request")
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorAuditLogger(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	var entries []AuditEntry
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		AuditLogger: func(c *gin.Context, entry AuditEntry) {
+			entries = append(entries, entry)
+		},
+	}))
+	g.POST("/account_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusCreated)
+	})
+
+	before := time.Now()
+	rec := doPost(t, g, "http://deepmap.ai/account_resource", gin.H{"name": "Wilma"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = doPost(t, g, "http://deepmap.ai/account_resource", gin.H{"nickname": "Wilma"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "createAccountResource", entries[0].OperationID)
+	assert.Equal(t, http.MethodPost, entries[0].Method)
+	assert.Equal(t, "/account_resource", entries[0].Path)
+	assert.Equal(t, http.StatusCreated, entries[0].Status)
+	assert.True(t, entries[0].Valid)
+	assert.NoError(t, entries[0].Error)
+	assert.False(t, entries[0].Time.Before(before))
+
+	assert.Equal(t, "createAccountResource", entries[1].OperationID)
+	assert.Equal(t, http.StatusBadRequest, entries[1].Status)
+	assert.False(t, entries[1].Valid)
+	assert.ErrorContains(t, entries[1].Error, "request body has an error")
+	assert.Empty(t, entries[1].Skipped)
+}
+
+func TestOapiRequestValidatorAuditLoggerSkipped(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	var entries []AuditEntry
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		AuditLogger: func(c *gin.Context, entry AuditEntry) {
+			entries = append(entries, entry)
+		},
+		Skipper: func(c *gin.Context) bool {
+			return c.Request.URL.Path == "/healthz"
+		},
+		PassThroughUnmatched: true,
+	}))
+	handler := func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+	g.GET("/healthz", handler)
+	g.GET("/unknown", handler)
+	g.GET("/resource", handler)
+
+	doGet(t, g, "http://deepmap.ai/healthz")
+	doGet(t, g, "http://deepmap.ai/unknown")
+	SetEnabled(false)
+	doGet(t, g, "http://deepmap.ai/resource?id=500")
+	SetEnabled(true)
+
+	require.Len(t, entries, 3)
+	for i, skipped := range []SkipReason{SkippedBySkipper, SkippedUnmatched, SkippedDisabled} {
+		assert.Equal(t, skipped, entries[i].Skipped)
+		assert.False(t, entries[i].Valid)
+		assert.Equal(t, http.StatusNoContent, entries[i].Status)
+	}
+	assert.NoError(t, entries[0].Error)
+	assert.ErrorContains(t, entries[1].Error, "no matching operation was found")
+	assert.NoError(t, entries[2].Error)
+}
+
+func TestOapiRequestValidatorAuditLoggerSkippedByExtension(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	var entries []AuditEntry
+	validated := 0
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		AuditLogger: func(c *gin.Context, entry AuditEntry) {
+			entries = append(entries, entry)
+		},
+		OnRequestValidated: func(c *gin.Context, operationID string, d time.Duration) {
+			validated++
+		},
+		UseValidationExtension: true,
+		ValidateOncePerRoute:   true,
+	}))
+	g.GET("/overridden_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Requests skipped by x-validation aren't counted as validated, so none
+	// marks the operation validated once either
+	for i := 0; i < 2; i++ {
+		rec := doGet(t, g, "http://deepmap.ai/overridden_resource?id=abc")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	assert.Equal(t, 0, validated)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, SkippedByExtension, entry.Skipped)
+		assert.False(t, entry.Valid)
+		assert.NoError(t, entry.Error)
+		assert.Equal(t, "getOverriddenResource", entry.OperationID)
+	}
+}
+
+func TestOapiRequestValidatorHostResolver(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		// The logical host is carried by a custom header
+		HostResolver: func(c *gin.Context) string {
+			return c.GetHeader("X-Tenant-Host")
+		},
+	}))
+
+	var host string
+	called := false
+	g.GET("/resource", func(c *gin.Context) {
+		called = true
+		host = c.Request.Host
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// The resolved host matches the spec's server
+	{
+		rec := doGetWithHeaders(t, g, "http://internal.local/resource", map[string]string{
+			"X-Tenant-Host": "deepmap.ai",
+		})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		assert.Equal(t, "internal.local", host, "Handler should see the original host")
+		called = false
+	}
+
+	// Without it, the request's own host is used, which doesn't
+	{
+		rec := doGet(t, g, "http://internal.local/resource")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorRejectDeprecatedParams(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	handler := func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		RejectDeprecatedParams: true,
+	}))
+	g.GET("/deprecated_parameter_resource", handler)
+
+	rec := doGet(t, g, "http://deepmap.ai/deprecated_parameter_resource?cursor=abc")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doGet(t, g, "http://deepmap.ai/deprecated_parameter_resource?page=2")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `parameter \"page\" in query is deprecated`)
+
+	// Deprecated parameters are accepted by default
+	g = gin.New()
+	g.Use(OapiRequestValidator(swagger))
+	g.GET("/deprecated_parameter_resource", handler)
+
+	rec = doGet(t, g, "http://deepmap.ai/deprecated_parameter_resource?page=2")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestOapiRequestValidatorContentParameter(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.GET("/content_parameter_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	get := func(filter string) *httptest.ResponseRecorder {
+		return doGet(t, g, "http://deepmap.ai/content_parameter_resource?filter="+url.QueryEscape(filter))
+	}
+
+	// A JSON value matching the schema passes
+	{
+		rec := get(`{"name": "Wilma", "limit": 10}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// One which doesn't, or isn't JSON, is rejected
+	for _, filter := range []string{`{"limit": 10}`, `{"name": "Wilma", "limit": 1000}`, `name=Wilma`} {
+		rec := get(filter)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, filter)
+		assert.Contains(t, rec.Body.String(), "parameter \\\"filter\\\" in query has an error", filter)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}
+
+func TestOapiRequestValidatorContentParameterDecoder(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		// Decodes filters written as name=value
+		ParamDecoder: func(param *openapi3.Parameter, values []string) (interface{}, *openapi3.Schema, error) {
+			name, value, ok := strings.Cut(values[0], "=")
+			if !ok {
+				return nil, nil, errors.New("expected name=value")
+			}
+			schema := param.Content.Get("application/json").Schema.Value
+			return map[string]interface{}{name: value}, schema, nil
+		},
+	}))
+	g.GET("/content_parameter_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doGet(t, g, "http://deepmap.ai/content_parameter_resource?filter=name%3DWilma")
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = doGet(t, g, "http://deepmap.ai/content_parameter_resource?filter=nickname%3DWilma")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "property \\\"name\\\" is missing")
+
+	rec = doGet(t, g, "http://deepmap.ai/content_parameter_resource?filter=Wilma")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "expected name=value")
+}
+
+func TestOapiRequestValidatorEnumErrorListsAllowedValues(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+	g.POST("/account_resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	rec := doPost(t, g, "http://deepmap.ai/account_resource", gin.H{"name": "Wilma", "role": "owner"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Contains(t, body["error"], `Error at "/role": value is not one of the allowed values ["admin","member"]`)
+}
+
+func BenchmarkOapiRequestValidator(b *testing.B) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(b, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{SilenceServersWarning: true}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	g.POST("/resource", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	b.Run("GET", func(b *testing.B) {
+		req := httptest.NewRequest(http.MethodGet, "http://deepmap.ai/resource?id=50", nil)
+		rec := httptest.NewRecorder()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			g.ServeHTTP(rec, req)
+		}
+		require.Equal(b, http.StatusNoContent, rec.Code)
+	})
+
+	b.Run("GET without context injection", func(b *testing.B) {
+		g := gin.New()
+		g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+			SilenceServersWarning:   true,
+			DisableContextInjection: true,
+		}))
+		g.GET("/resource", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodGet, "http://deepmap.ai/resource?id=50", nil)
+		rec := httptest.NewRecorder()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			g.ServeHTTP(rec, req)
+		}
+		require.Equal(b, http.StatusNoContent, rec.Code)
+	})
+
+	b.Run("POST", func(b *testing.B) {
+		body := []byte(`{"name": "Wilma"}`)
+		req := httptest.NewRequest(http.MethodPost, "http://deepmap.ai/resource", nil)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			g.ServeHTTP(rec, req)
+		}
+		require.Equal(b, http.StatusNoContent, rec.Code)
+	})
+}
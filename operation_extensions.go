@@ -0,0 +1,88 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// operationExtensions holds the parsed extensions of an operation which the
+// middleware acts on
+type operationExtensions struct {
+	validation        operationValidation
+	mutuallyExclusive [][]string
+}
+
+// specExtensions holds the parsed extensions of every operation of a spec.
+// They're parsed once, when the middleware is created, so that a malformed
+// extension is reported to the spec's author rather than to every client.
+type specExtensions map[*openapi3.Operation]operationExtensions
+
+// parseSpecExtensions parses the extensions of the operations of swagger
+// which options make the middleware act on.
+func parseSpecExtensions(swagger *openapi3.T, options *Options) (specExtensions, error) {
+	extensions := make(specExtensions)
+	if swagger.Paths == nil {
+		return extensions, nil
+	}
+	for path, pathItem := range swagger.Paths.Map() {
+		for method, operation := range pathItem.Operations() {
+			parsed, err := parseOperationExtensions(operation, options)
+			if err != nil {
+				return nil, fmt.Errorf("error in operation %s %s: %w", method, path, err)
+			}
+			extensions[operation] = parsed
+		}
+	}
+	return extensions, nil
+}
+
+// forOperation returns the parsed extensions of operation. Without parsed
+// extensions, such as when ValidateRequestFromContext is called directly,
+// they're parsed now.
+func (e specExtensions) forOperation(operation *openapi3.Operation, options *Options) (operationExtensions, error) {
+	if e == nil {
+		return parseOperationExtensions(operation, options)
+	}
+	return e[operation], nil
+}
+
+// parseOperationExtensions parses the extensions of operation which options
+// make the middleware act on.
+func parseOperationExtensions(operation *openapi3.Operation, options *Options) (operationExtensions, error) {
+	var parsed operationExtensions
+	if options == nil {
+		return parsed, nil
+	}
+	if options.UseValidationExtension {
+		validation, err := operationValidationFor(operation)
+		if err != nil {
+			return parsed, err
+		}
+		parsed.validation = validation
+	}
+	if options.EnforceMutuallyExclusiveParams {
+		if raw, ok := operation.Extensions[MutuallyExclusiveExtension]; ok {
+			groups, err := mutuallyExclusiveGroups(raw)
+			if err != nil {
+				return parsed, err
+			}
+			parsed.mutuallyExclusive = groups
+		}
+	}
+	return parsed, nil
+}
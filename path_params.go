@@ -0,0 +1,92 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// pathTemplateVariable matches a variable of a path template, such as {id}
+var pathTemplateVariable = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// repeatedPathParamValues returns every value of the path parameters which
+// appear more than once in the path template, such as id in /a/{id}/b/{id}.
+// The router only reports one value per name, so the others would otherwise
+// go unvalidated. The values of the catchAll parameters, see
+// CatchAllExtension, may span several segments. It returns nil when no
+// parameter is repeated.
+func repeatedPathParamValues(req *http.Request, template string, catchAll []string) map[string][]string {
+	matches := pathTemplateVariable.FindAllStringSubmatchIndex(template, -1)
+	if len(matches) < 2 {
+		return nil
+	}
+
+	spans := make(map[string]bool, len(catchAll))
+	for _, name := range catchAll {
+		spans[name] = true
+	}
+
+	names := make([]string, len(matches))
+	seen := make(map[string]bool, len(matches))
+	repeated := false
+	var pattern strings.Builder
+	last := 0
+	for i, m := range matches {
+		names[i] = template[m[2]:m[3]]
+		repeated = repeated || seen[names[i]]
+		seen[names[i]] = true
+		pattern.WriteString(regexp.QuoteMeta(template[last:m[0]]))
+		if spans[names[i]] {
+			pattern.WriteString(`(.+)`)
+		} else {
+			pattern.WriteString(`([^/]+)`)
+		}
+		last = m[1]
+	}
+	if !repeated {
+		return nil
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString(`$`)
+
+	// The request path may be prefixed by the server's base path, so only
+	// the end of it is matched.
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil
+	}
+	submatches := re.FindStringSubmatch(req.URL.EscapedPath())
+	if submatches == nil {
+		return nil
+	}
+
+	values := make(map[string][]string)
+	for i, name := range names {
+		value, err := url.PathUnescape(submatches[i+1])
+		if err != nil {
+			value = submatches[i+1]
+		}
+		values[name] = append(values[name], value)
+	}
+	for name, v := range values {
+		if len(v) < 2 {
+			delete(values, name)
+		}
+	}
+	return values
+}
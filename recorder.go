@@ -0,0 +1,93 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidationRecord is the outcome of validating a request, as kept by a
+// ValidationRecorder.
+type ValidationRecord struct {
+	Time        time.Time `json:"time"`
+	OperationID string    `json:"operationId,omitempty"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	Status      int       `json:"status"`
+	Valid       bool      `json:"valid"`
+	// Errors lists every validation error, when there were several
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ValidationRecorder keeps the outcome of the last validated requests in a
+// ring buffer, for a debug endpoint to expose. Enable it by setting
+// Options.AuditLogger to its Record method, or chaining that with another
+// logger with ChainAuditLoggers.
+type ValidationRecorder struct {
+	mu      sync.Mutex
+	records []ValidationRecord
+	next    int
+	full    bool
+}
+
+// NewValidationRecorder creates a ValidationRecorder keeping the last size
+// records.
+func NewValidationRecorder(size int) *ValidationRecorder {
+	if size < 1 {
+		size = 1
+	}
+	return &ValidationRecorder{records: make([]ValidationRecord, size)}
+}
+
+// Record stores entry, evicting the oldest record when the buffer is full.
+// It's an AuditLogger.
+func (r *ValidationRecorder) Record(c *gin.Context, entry AuditEntry) {
+	record := ValidationRecord{
+		Time:        entry.Time,
+		OperationID: entry.OperationID,
+		Method:      entry.Method,
+		Path:        entry.Path,
+		Status:      entry.Status,
+		Valid:       entry.Valid,
+	}
+	if me := GetMultiError(c); me != nil {
+		for _, err := range me {
+			record.Errors = append(record.Errors, err.Error())
+		}
+	} else if entry.Error != nil {
+		record.Errors = []string{entry.Error.Error()}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	r.full = r.full || r.next == 0
+}
+
+// Records returns the stored records, oldest first.
+func (r *ValidationRecorder) Records() []ValidationRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]ValidationRecord(nil), r.records[:r.next]...)
+	}
+	records := make([]ValidationRecord, 0, len(r.records))
+	records = append(records, r.records[r.next:]...)
+	return append(records, r.records[:r.next]...)
+}
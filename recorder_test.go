@@ -0,0 +1,110 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationRecorder(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	recorder := NewValidationRecorder(2)
+
+	g := gin.New()
+	// The debug endpoint is registered ahead of the validator
+	g.GET("/debug/validation", func(c *gin.Context) {
+		c.JSON(http.StatusOK, recorder.Records())
+	})
+	api := g.Group("/", OapiRequestValidatorWithOptions(swagger, &Options{
+		AuditLogger:      recorder.Record,
+		CollectAllErrors: true,
+	}))
+	api.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	api.GET("/multiparamresource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	assert.Empty(t, recorder.Records())
+
+	doGet(t, g, "http://deepmap.ai/resource?id=500")
+	doGet(t, g, "http://deepmap.ai/resource?id=50")
+	doGet(t, g, "http://deepmap.ai/multiparamresource")
+
+	rec := doGet(t, g, "http://deepmap.ai/debug/validation")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var records []ValidationRecord
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+
+	// Only the last two are kept, oldest first
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "/resource", records[0].Path)
+	assert.Equal(t, http.StatusNoContent, records[0].Status)
+	assert.True(t, records[0].Valid)
+	assert.Empty(t, records[0].Errors)
+
+	assert.Equal(t, "/multiparamresource", records[1].Path)
+	assert.Equal(t, http.StatusBadRequest, records[1].Status)
+	assert.False(t, records[1].Valid)
+	require.Len(t, records[1].Errors, 2)
+	assert.Contains(t, records[1].Errors[0], `parameter "id" in query has an error`)
+	assert.Contains(t, records[1].Errors[1], `parameter "id2" in query has an error`)
+}
+
+func TestChainAuditLoggers(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	recorder := NewValidationRecorder(10)
+	var logged []AuditEntry
+
+	g := gin.New()
+	g.Use(OapiRequestValidatorWithOptions(swagger, &Options{
+		AuditLogger: ChainAuditLoggers(
+			recorder.Record,
+			nil,
+			func(c *gin.Context, entry AuditEntry) {
+				logged = append(logged, entry)
+			},
+		),
+	}))
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	doGet(t, g, "http://deepmap.ai/resource?id=50")
+	doGet(t, g, "http://deepmap.ai/resource?id=500")
+
+	// Both loggers see every request
+	records := recorder.Records()
+	require.Len(t, records, 2)
+	require.Len(t, logged, 2)
+	assert.True(t, records[0].Valid)
+	assert.True(t, logged[0].Valid)
+	assert.False(t, records[1].Valid)
+	assert.False(t, logged[1].Valid)
+	assert.Equal(t, http.StatusBadRequest, logged[1].Status)
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// SpecCache stores specs which were loaded and validated, so that processes
+// which start often, such as serverless functions, can skip validating an
+// unchanged spec. Keys are hashes of the spec's source, values are the spec
+// serialized as JSON. Only the main file is hashed, so specs with external
+// references, whose changes would go unnoticed, are rejected.
+type SpecCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, spec []byte)
+}
+
+// OapiValidatorFromYamlFileWithCache creates a validator middleware from a
+// YAML file path, like OapiValidatorFromYamlFile, but also validates the
+// spec itself. A spec whose hash is found in cache was validated before, so
+// it is loaded from its cached serialization without being validated again.
+func OapiValidatorFromYamlFileWithCache(path string, cache SpecCache) (gin.HandlerFunc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %s", path, err)
+	}
+	data = stripBOM(data)
+	location := &url.URL{Path: filepath.ToSlash(path)}
+
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	if cached, ok := cache.Get(key); ok {
+		swagger, err := openapi3.NewLoader().LoadFromDataWithPath(cached, location)
+		if err == nil {
+			return OapiRequestValidator(swagger), nil
+		}
+		// An unreadable cache entry is replaced below
+	}
+
+	// The loader disallows external references, see SpecCache
+	swagger, err := openapi3.NewLoader().LoadFromDataWithPath(data, location)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s as Swagger YAML: %s",
+			path, err)
+	}
+	if err := swagger.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("error validating %s: %w", path, err)
+	}
+	serialized, err := swagger.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("error serializing %s: %w", path, err)
+	}
+	cache.Set(key, serialized)
+	return OapiRequestValidator(swagger), nil
+}
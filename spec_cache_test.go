@@ -0,0 +1,159 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSpecCache struct {
+	entries map[string][]byte
+	gets    int
+	sets    int
+}
+
+func (m *mockSpecCache) Get(key string) ([]byte, bool) {
+	m.gets++
+	spec, ok := m.entries[key]
+	return spec, ok
+}
+
+func (m *mockSpecCache) Set(key string, spec []byte) {
+	m.sets++
+	m.entries[key] = spec
+}
+
+var testCachedSchema = []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /resource:
+    get:
+      operationId: getResource
+      parameters:
+        - name: id
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        '204':
+          description: no content
+`)
+
+func TestOapiValidatorFromYamlFileWithCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	require.NoError(t, os.WriteFile(path, testCachedSchema, 0o600))
+
+	cache := &mockSpecCache{entries: map[string][]byte{}}
+
+	// The first load validates the spec and stores it
+	_, err := OapiValidatorFromYamlFileWithCache(path, cache)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cache.gets)
+	assert.Equal(t, 1, cache.sets)
+
+	// The second one is served from the cache
+	validator, err := OapiValidatorFromYamlFileWithCache(path, cache)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.gets)
+	assert.Equal(t, 1, cache.sets)
+
+	g := gin.New()
+	g.Use(validator)
+	g.GET("/resource", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+	assert.Equal(t, http.StatusNoContent, doGet(t, g, "http://deepmap.ai/resource").Code)
+	assert.Equal(t, http.StatusBadRequest, doGet(t, g, "http://deepmap.ai/resource?id=500").Code)
+}
+
+func TestOapiValidatorFromYamlFileWithCacheSkipsValidation(t *testing.T) {
+	// This spec loads, but fails validation because of its unknown type
+	invalid := []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /resource:
+    get:
+      parameters:
+        - name: id
+          in: query
+          schema:
+            type: strin
+      responses:
+        '204':
+          description: no content
+`)
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	require.NoError(t, os.WriteFile(path, invalid, 0o600))
+
+	_, err := OapiValidatorFromYamlFileWithCache(path, &mockSpecCache{entries: map[string][]byte{}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error validating")
+
+	// A cache hit for the same source isn't validated again
+	sum := sha256.Sum256(invalid)
+	cache := &mockSpecCache{entries: map[string][]byte{
+		hex.EncodeToString(sum[:]): []byte(`{"openapi":"3.0.0","info":{"version":"1.0.0","title":"TestServer"},"paths":{}}`),
+	}}
+	_, err = OapiValidatorFromYamlFileWithCache(path, cache)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cache.sets)
+}
+
+func TestOapiValidatorFromYamlFileWithCacheExternalRef(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spec.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /resource:
+    get:
+      parameters:
+        - name: id
+          in: query
+          schema:
+            $ref: './id.yaml'
+      responses:
+        '204':
+          description: no content
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id.yaml"), []byte("type: integer\n"), 0o600))
+
+	// Only the main file would be hashed, so a change to id.yaml would be
+	// missed
+	cache := &mockSpecCache{entries: map[string][]byte{}}
+	_, err := OapiValidatorFromYamlFileWithCache(path, cache)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disallowed external reference")
+	assert.Equal(t, 0, cache.sets)
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+// standardHeaders are accepted by Options.RejectUnknownHeaders without
+// being declared in the spec.
+var standardHeaders = map[string]bool{
+	"Accept":                    true,
+	"Accept-Charset":            true,
+	"Accept-Encoding":           true,
+	"Accept-Language":           true,
+	"Authorization":             true,
+	"Cache-Control":             true,
+	"Connection":                true,
+	"Content-Encoding":          true,
+	"Content-Language":          true,
+	"Content-Length":            true,
+	"Content-Type":              true,
+	"Cookie":                    true,
+	"Date":                      true,
+	"Dnt":                       true,
+	"Expect":                    true,
+	"Forwarded":                 true,
+	"Host":                      true,
+	"If-Match":                  true,
+	"If-Modified-Since":         true,
+	"If-None-Match":             true,
+	"If-Range":                  true,
+	"If-Unmodified-Since":       true,
+	"Origin":                    true,
+	"Pragma":                    true,
+	"Range":                     true,
+	"Referer":                   true,
+	"Priority":                  true,
+	"Te":                        true,
+	"Traceparent":               true,
+	"Tracestate":                true,
+	"Trailer":                   true,
+	"Transfer-Encoding":         true,
+	"Upgrade":                   true,
+	"Upgrade-Insecure-Requests": true,
+	"User-Agent":                true,
+	"Via":                       true,
+	"X-Forwarded-For":           true,
+	"X-Forwarded-Host":          true,
+	"X-Forwarded-Proto":         true,
+	"X-Real-Ip":                 true,
+	"X-Request-Id":              true,
+}
+
+// standardHeaderPrefixes are prefixes of headers accepted like
+// standardHeaders, such as the Sec-Fetch-* and Sec-Ch-Ua* headers which
+// browsers send.
+var standardHeaderPrefixes = []string{"Sec-"}
+
+// validateKnownHeaders returns an error naming the first header, in
+// alphabetical order, which is neither standard, allowed nor declared by the
+// route. Allowed names ending with * are prefixes.
+func validateKnownHeaders(header http.Header, route *routers.Route, allowed []string) error {
+	known := make(map[string]bool)
+	prefixes := append([]string(nil), standardHeaderPrefixes...)
+	for _, name := range allowed {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			prefixes = append(prefixes, textproto.CanonicalMIMEHeaderKey(prefix))
+			continue
+		}
+		known[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	addParameters := func(parameters openapi3.Parameters) {
+		for _, parameterRef := range parameters {
+			if parameterRef.Value != nil && parameterRef.Value.In == openapi3.ParameterInHeader {
+				known[textproto.CanonicalMIMEHeaderKey(parameterRef.Value.Name)] = true
+			}
+		}
+	}
+	addParameters(route.PathItem.Parameters)
+	addParameters(route.Operation.Parameters)
+
+	security := route.Spec.Security
+	if route.Operation.Security != nil {
+		security = *route.Operation.Security
+	}
+	if route.Spec.Components == nil {
+		security = nil
+	}
+	for _, requirement := range security {
+		for name := range requirement {
+			schemeRef, ok := route.Spec.Components.SecuritySchemes[name]
+			if !ok || schemeRef.Value == nil {
+				continue
+			}
+			if scheme := schemeRef.Value; scheme.Type == "apiKey" && scheme.In == openapi3.ParameterInHeader {
+				known[textproto.CanonicalMIMEHeaderKey(scheme.Name)] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !standardHeaders[name] && !known[name] && !hasAnyPrefix(name, prefixes) {
+			return fmt.Errorf("header %q is not allowed", name)
+		}
+	}
+	return nil
+}
+
+// hasAnyPrefix reports whether name starts with any of prefixes
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidatedParamsKey is the gin context key under which the parameters of a
+// validated request are stored, when Options.StoreValidatedParams is set
+const ValidatedParamsKey = "oapi-codegen/validated-params"
+
+// GetValidatedParams returns the typed parameters of a request which passed
+// validation, by name, when Options.StoreValidatedParams is set. On a name
+// clash, path beats query, header and cookie, see GetValidatedParamsIn.
+func GetValidatedParams(c *gin.Context) map[string]interface{} {
+	byLocation := validatedParamsByLocation(c)
+	if byLocation == nil {
+		return nil
+	}
+	params := make(map[string]interface{})
+	for _, in := range []string{openapi3.ParameterInCookie, openapi3.ParameterInHeader, openapi3.ParameterInQuery, openapi3.ParameterInPath} {
+		for name, value := range byLocation[in] {
+			params[name] = value
+		}
+	}
+	return params
+}
+
+// GetValidatedParamsIn returns the validated parameters of a request in one
+// location, such as openapi3.ParameterInQuery, by name.
+func GetValidatedParamsIn(c *gin.Context, in string) map[string]interface{} {
+	return validatedParamsByLocation(c)[in]
+}
+
+func validatedParamsByLocation(c *gin.Context) map[string]map[string]interface{} {
+	value, _ := c.Get(ValidatedParamsKey)
+	params, _ := value.(map[string]map[string]interface{})
+	return params
+}
+
+// validatedParams returns the typed values of the route's parameters set by
+// req, by location and name. Operation parameters take precedence over path
+// item ones. Nested arrays and objects are kept as strings, and nested
+// deepObject properties left out.
+func validatedParams(req *http.Request, route *routers.Route, pathParams map[string]string) map[string]map[string]interface{} {
+	params := make(map[string]map[string]interface{})
+	query := req.URL.Query()
+	for _, parameters := range []openapi3.Parameters{route.PathItem.Parameters, route.Operation.Parameters} {
+		for _, parameterRef := range parameters {
+			parameter := parameterRef.Value
+			if parameter == nil {
+				continue
+			}
+			var values []string
+			switch parameter.In {
+			case openapi3.ParameterInPath:
+				if value, ok := pathParams[parameter.Name]; ok {
+					values = []string{value}
+				}
+			case openapi3.ParameterInQuery:
+				if isObjectParam(parameter) {
+					if object := queryObjectValue(parameter, query); object != nil {
+						setParam(params, parameter, object)
+						continue
+					}
+				}
+				values = query[parameter.Name]
+			case openapi3.ParameterInHeader:
+				values = req.Header.Values(parameter.Name)
+			case openapi3.ParameterInCookie:
+				if cookie, err := req.Cookie(parameter.Name); err == nil {
+					values = []string{cookie.Value}
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			setParam(params, parameter, paramValue(parameter, values))
+		}
+	}
+	return params
+}
+
+// setParam stores the value of parameter in params
+func setParam(params map[string]map[string]interface{}, parameter *openapi3.Parameter, value interface{}) {
+	if params[parameter.In] == nil {
+		params[parameter.In] = make(map[string]interface{})
+	}
+	params[parameter.In][parameter.Name] = value
+}
+
+// isObjectParam tells whether parameter has an object schema
+func isObjectParam(parameter *openapi3.Parameter) bool {
+	return parameter.Schema != nil && parameter.Schema.Value != nil &&
+		parameter.Schema.Value.Type.Is(openapi3.TypeObject)
+}
+
+// queryObjectValue returns the properties of an exploded form or deepObject
+// query parameter, which are spread over several query parameters, or nil
+// if there are none or parameter is serialized otherwise
+func queryObjectValue(parameter *openapi3.Parameter, query url.Values) map[string]interface{} {
+	method, err := parameter.SerializationMethod()
+	if err != nil || !method.Explode {
+		return nil
+	}
+	schema := parameter.Schema.Value
+	object := make(map[string]interface{})
+	switch method.Style {
+	case openapi3.SerializationDeepObject:
+		prefix := parameter.Name + "["
+		for key, values := range query {
+			property, ok := strings.CutPrefix(key, prefix)
+			if !ok || !strings.HasSuffix(property, "]") || len(values) == 0 {
+				continue
+			}
+			property = strings.TrimSuffix(property, "]")
+			if strings.ContainsAny(property, "[]") {
+				continue
+			}
+			object[property] = propertyValue(schema, property, values)
+		}
+	case openapi3.SerializationForm:
+		for property := range schema.Properties {
+			if values := query[property]; len(values) > 0 {
+				object[property] = propertyValue(schema, property, values)
+			}
+		}
+	default:
+		return nil
+	}
+	if len(object) == 0 {
+		return nil
+	}
+	return object
+}
+
+// propertyValue converts the raw values of a property of an object schema
+func propertyValue(schema *openapi3.Schema, property string, values []string) interface{} {
+	propertyRef := schema.Properties[property]
+	if propertyRef == nil || propertyRef.Value == nil {
+		return values[0]
+	}
+	if !propertyRef.Value.Type.Is(openapi3.TypeArray) {
+		return primitiveValue(propertyRef.Value, values[0])
+	}
+	if propertyRef.Value.Items == nil || propertyRef.Value.Items.Value == nil {
+		return values[0]
+	}
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = primitiveValue(propertyRef.Value.Items.Value, value)
+	}
+	return items
+}
+
+// paramValue converts the raw values of a parameter after its schema
+func paramValue(parameter *openapi3.Parameter, values []string) interface{} {
+	if parameter.Schema == nil || parameter.Schema.Value == nil {
+		if parameter.Content.Get("application/json") != nil {
+			var value interface{}
+			if err := json.Unmarshal([]byte(values[0]), &value); err == nil {
+				return value
+			}
+		}
+		return values[0]
+	}
+
+	schema := parameter.Schema.Value
+	method, err := parameter.SerializationMethod()
+	if err != nil {
+		return values[0]
+	}
+	if parameter.In == openapi3.ParameterInPath {
+		values = []string{trimPathPrefix(parameter.Name, method, values[0])}
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeObject):
+		return objectValue(schema, parameter.Name, method, values[0])
+	case !schema.Type.Is(openapi3.TypeArray):
+		return primitiveValue(schema, values[0])
+	case schema.Items == nil || schema.Items.Value == nil:
+		return values[0]
+	}
+	if len(values) == 1 {
+		values = splitValue(parameter.Name, method, false, values[0])
+	}
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = primitiveValue(schema.Items.Value, value)
+	}
+	return items
+}
+
+// trimPathPrefix strips the prefix of a label or matrix path parameter
+func trimPathPrefix(name string, method *openapi3.SerializationMethod, value string) string {
+	switch method.Style {
+	case openapi3.SerializationLabel:
+		return strings.TrimPrefix(value, ".")
+	case openapi3.SerializationMatrix:
+		if trimmed, ok := strings.CutPrefix(value, ";"+name+"="); ok {
+			return trimmed
+		}
+		return strings.TrimPrefix(value, ";")
+	}
+	return value
+}
+
+// splitValue splits the serialized value of an array or object parameter
+// into its elements, once any path prefix is stripped. Those of exploded
+// objects are key=value pairs.
+func splitValue(name string, method *openapi3.SerializationMethod, object bool, value string) []string {
+	switch {
+	case method.Style == openapi3.SerializationSpaceDelimited:
+		return strings.Split(value, " ")
+	case method.Style == openapi3.SerializationPipeDelimited:
+		return strings.Split(value, "|")
+	case !method.Explode:
+		return strings.Split(value, ",")
+	case method.Style == openapi3.SerializationLabel:
+		return strings.Split(value, ".")
+	case method.Style == openapi3.SerializationMatrix && object:
+		return strings.Split(value, ";")
+	case method.Style == openapi3.SerializationMatrix:
+		return strings.Split(value, ";"+name+"=")
+	}
+	return strings.Split(value, ",")
+}
+
+// objectValue decodes the properties of an object parameter serialized as a
+// single value, typing them after their schema
+func objectValue(schema *openapi3.Schema, name string, method *openapi3.SerializationMethod, value string) interface{} {
+	elements := splitValue(name, method, true, value)
+	object := make(map[string]interface{})
+	if method.Explode {
+		for _, element := range elements {
+			property, propertyRaw, ok := strings.Cut(element, "=")
+			if !ok {
+				return value
+			}
+			object[property] = propertyValue(schema, property, []string{propertyRaw})
+		}
+		return object
+	}
+	if len(elements)%2 != 0 {
+		return value
+	}
+	for i := 0; i < len(elements); i += 2 {
+		object[elements[i]] = propertyValue(schema, elements[i], []string{elements[i+1]})
+	}
+	return object
+}
+
+// primitiveValue parses value as the type of schema, leaving it a string if
+// it isn't a primitive or doesn't parse
+func primitiveValue(schema *openapi3.Schema, value string) interface{} {
+	switch {
+	case schema.Type.Is(openapi3.TypeInteger):
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	case schema.Type.Is(openapi3.TypeNumber):
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case schema.Type.Is(openapi3.TypeBoolean):
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
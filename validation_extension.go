@@ -0,0 +1,60 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ValidationExtension is the operation extension which overrides the
+// validation of requests to that operation, when
+// Options.UseValidationExtension is set. For instance, this operation's
+// body isn't validated:
+//
+//	x-validation:
+//	  excludeRequestBody: true
+//
+// Supported settings are skipRequest, which skips validation altogether,
+// excludeRequestBody and excludeRequestQueryParams. Other settings are
+// ignored.
+const ValidationExtension = "x-validation"
+
+// operationValidation holds the settings of an x-validation extension
+type operationValidation struct {
+	SkipRequest               bool `json:"skipRequest"`
+	ExcludeRequestBody        bool `json:"excludeRequestBody"`
+	ExcludeRequestQueryParams bool `json:"excludeRequestQueryParams"`
+}
+
+// operationValidationFor reads the x-validation extension of an operation,
+// returning the zero value if it has none.
+func operationValidationFor(operation *openapi3.Operation) (operationValidation, error) {
+	var settings operationValidation
+	raw, ok := operation.Extensions[ValidationExtension]
+	if !ok {
+		return settings, nil
+	}
+	data, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return settings, fmt.Errorf("error reading %s: %w", ValidationExtension, err)
+	}
+	return settings, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// XWebhooksExtension is the spec extension holding webhook path items, for
+// specs which model webhooks outside of the standard `paths`.
+const XWebhooksExtension = "x-webhooks"
+
+// withXWebhooks returns a copy of swagger whose paths also contain the path
+// items declared in its x-webhooks extension, so that they can be routed and
+// validated like any other operation. swagger itself is left untouched.
+func withXWebhooks(swagger *openapi3.T) (*openapi3.T, error) {
+	raw, ok := swagger.Extensions[XWebhooksExtension]
+	if !ok {
+		return swagger, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", XWebhooksExtension, err)
+	}
+	var webhooks map[string]*openapi3.PathItem
+	if err := json.Unmarshal(data, &webhooks); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", XWebhooksExtension, err)
+	}
+
+	// Resolve any references to components in the webhook path items
+	// separately, as the rest of the spec has already been resolved.
+	webhookSpec := &openapi3.T{
+		OpenAPI:    swagger.OpenAPI,
+		Components: swagger.Components,
+		Info:       swagger.Info,
+		Paths:      openapi3.NewPaths(),
+	}
+	for path, pathItem := range webhooks {
+		webhookSpec.Paths.Set(path, pathItem)
+	}
+	if err := openapi3.NewLoader().ResolveRefsIn(webhookSpec, nil); err != nil {
+		return nil, fmt.Errorf("error resolving %s: %w", XWebhooksExtension, err)
+	}
+
+	spec := *swagger
+	spec.Paths = openapi3.NewPaths()
+	for path, pathItem := range swagger.Paths.Map() {
+		spec.Paths.Set(path, pathItem)
+	}
+	for path, pathItem := range webhooks {
+		if spec.Paths.Value(path) != nil {
+			return nil, fmt.Errorf("%s path %s is already declared in paths", XWebhooksExtension, path)
+		}
+		spec.Paths.Set(path, pathItem)
+	}
+	return &spec, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testXWebhooksSchema = []byte(`
+openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: TestServer
+paths:
+  /resource:
+    get:
+      operationId: getResource
+      responses:
+        '204':
+          description: no content
+x-webhooks:
+  /webhooks/{tenant}/payment:
+    post:
+      operationId: paymentWebhook
+      parameters:
+        - name: tenant
+          in: path
+          required: true
+          schema:
+            type: string
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Payment'
+      responses:
+        '204':
+          description: no content
+components:
+  schemas:
+    Payment:
+      type: object
+      required:
+        - amount
+      properties:
+        amount:
+          type: integer
+          minimum: 1
+`)
+
+func TestOapiRequestValidatorXWebhooks(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData(testXWebhooksSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+
+	options := Options{
+		IncludeXWebhooks: true,
+	}
+	g.Use(OapiRequestValidatorWithOptions(swagger, &options))
+
+	called := false
+	g.POST("/webhooks/:tenant/payment", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's post a good payload, it should pass
+	{
+		rec := doPost(t, g, "http://deepmap.ai/webhooks/acme/payment", map[string]interface{}{"amount": 10})
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	// Post a payload violating the referenced schema
+	{
+		rec := doPost(t, g, "http://deepmap.ai/webhooks/acme/payment", map[string]interface{}{"amount": 0})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// The spec we were given shouldn't have been modified
+	assert.Nil(t, swagger.Paths.Value("/webhooks/{tenant}/payment"))
+
+	// Without the option, the webhook path isn't routable
+	{
+		g := gin.New()
+		g.Use(OapiRequestValidator(swagger))
+		rec := doPost(t, g, "http://deepmap.ai/webhooks/acme/payment", map[string]interface{}{"amount": 10})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// String formats for the JSON representation of protobuf well-known types,
+// as produced by gRPC-gateway.
+const (
+	// FormatGoogleDateTime is a google.protobuf.Timestamp, an RFC 3339 date
+	// time such as `1972-01-01T10:00:20.021Z`
+	FormatGoogleDateTime = "google-datetime"
+	// FormatGoogleDuration is a google.protobuf.Duration, a number of
+	// seconds with an `s` suffix such as `1.5s`
+	FormatGoogleDuration = "google-duration"
+	// FormatGoogleFieldMask is a google.protobuf.FieldMask, a comma separated
+	// list of lowerCamelCase field paths such as `user.displayName,photo`
+	FormatGoogleFieldMask = "google-fieldmask"
+)
+
+var (
+	googleDurationPattern  = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,9})?s$`)
+	googleFieldMaskPattern = regexp.MustCompile(`^([a-z][a-zA-Z0-9]*(\.[a-z][a-zA-Z0-9]*)*(,[a-z][a-zA-Z0-9]*(\.[a-z][a-zA-Z0-9]*)*)*)?$`)
+)
+
+// DefineWellKnownTypeFormats opts in validation of the FormatGoogleDateTime,
+// FormatGoogleDuration and FormatGoogleFieldMask string formats. As with
+// openapi3.DefineIPv4Format, this registers the formats globally.
+func DefineWellKnownTypeFormats() {
+	openapi3.DefineStringFormatCallback(FormatGoogleDateTime, validateGoogleDateTime)
+	openapi3.DefineStringFormatCallback(FormatGoogleDuration, validateGoogleDuration)
+	openapi3.DefineStringFormatCallback(FormatGoogleFieldMask, validateGoogleFieldMask)
+}
+
+func validateGoogleDateTime(value string) error {
+	if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+		return fmt.Errorf("not a valid RFC 3339 timestamp: %w", err)
+	}
+	return nil
+}
+
+func validateGoogleDuration(value string) error {
+	if !googleDurationPattern.MatchString(value) {
+		return fmt.Errorf("not a valid duration, expected seconds with an 's' suffix")
+	}
+	return nil
+}
+
+func validateGoogleFieldMask(value string) error {
+	if !googleFieldMaskPattern.MatchString(value) {
+		return fmt.Errorf("not a valid field mask")
+	}
+	return nil
+}
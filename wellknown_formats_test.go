@@ -0,0 +1,106 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefineWellKnownTypeFormats(t *testing.T) {
+	// The formats are registered globally, restore the previous ones so as
+	// not to leak into other tests
+	previous := make(map[string]openapi3.Format, len(openapi3.SchemaStringFormats))
+	for name, format := range openapi3.SchemaStringFormats {
+		previous[name] = format
+	}
+	t.Cleanup(func() {
+		openapi3.SchemaStringFormats = previous
+	})
+	DefineWellKnownTypeFormats()
+
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	called := false
+	g.POST("/well_known_types_resource", func(c *gin.Context) {
+		called = true
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's send valid values, they should pass
+	{
+		body := map[string]interface{}{
+			"createTime": "1972-01-01T10:00:20.021Z",
+			"ttl":        "1.5s",
+			"updateMask": "user.displayName,photo",
+		}
+		rec := doPost(t, g, "http://deepmap.ai/well_known_types_resource", body)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		called = false
+	}
+
+	for name, body := range map[string]map[string]interface{}{
+		"timestamp without a zone": {"createTime": "1972-01-01T10:00:20"},
+		"timestamp out of range":   {"createTime": "1972-13-01T10:00:20Z"},
+		"duration without unit":    {"ttl": "1.5"},
+		"duration in minutes":      {"ttl": "2m"},
+		"field mask in snake case": {"updateMask": "display_name"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := doPost(t, g, "http://deepmap.ai/well_known_types_resource", body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.False(t, called, "Handler should not have been called")
+		})
+	}
+
+	// The middleware only validates requests, but the formats apply to
+	// responses validated with openapi3filter just as well
+	router, err := newRouter(swagger)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "http://deepmap.ai/well_known_types_resource", nil)
+	route, pathParams, err := router.FindRoute(req)
+	require.NoError(t, err)
+	validateResponse := func(body string) error {
+		return openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+			},
+			Status: http.StatusOK,
+			Header: http.Header{"Content-Type": {"application/json"}},
+			Body:   io.NopCloser(bytes.NewBufferString(body)),
+		})
+	}
+	assert.NoError(t, validateResponse(`{"createTime": "1972-01-01T10:00:20.021Z"}`))
+	err = validateResponse(`{"createTime": "1972-01-01T10:00:20"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a valid RFC 3339 timestamp")
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 DeepMap, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ginmiddleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doPostXML(t *testing.T, handler http.Handler, rawURL string, body string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(http.MethodPost, rawURL, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Could not construct a request for URL %s: %v", rawURL, err)
+	}
+	r.Header.Set("content-type", "application/xml")
+
+	tt := httptest.NewRecorder()
+
+	handler.ServeHTTP(tt, r)
+
+	return tt
+}
+
+func TestXMLBodyDecoder(t *testing.T) {
+	openapi3filter.RegisterBodyDecoder("application/xml", XMLBodyDecoder)
+	defer openapi3filter.UnregisterBodyDecoder("application/xml")
+
+	swagger, err := openapi3.NewLoader().LoadFromData(testSchema)
+	require.NoError(t, err, "Error initializing swagger")
+
+	g := gin.New()
+	g.Use(OapiRequestValidator(swagger))
+
+	var received string
+	called := false
+	g.POST("/xml_resource", func(c *gin.Context) {
+		called = true
+		body, err := io.ReadAll(c.Request.Body)
+		require.NoError(t, err)
+		received = string(body)
+		c.AbortWithStatus(http.StatusNoContent)
+	})
+
+	// Let's send a good body, it should pass and still be readable by the
+	// handler
+	{
+		body := `<pet id="7"><name>Rex</name><age>3</age><tags><tag>good</tag><tag>boy</tag></tags></pet>`
+		rec := doPostXML(t, g, "http://deepmap.ai/xml_resource", body)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.True(t, called, "Handler should have been called")
+		assert.Equal(t, body, received)
+		called = false
+	}
+
+	// Send an element which violates the schema
+	{
+		rec := doPostXML(t, g, "http://deepmap.ai/xml_resource", `<pet><name>Rex</name><age>-1</age></pet>`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send an element of the wrong type
+	{
+		rec := doPostXML(t, g, "http://deepmap.ai/xml_resource", `<pet id="seven"><name>Rex</name></pet>`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Leave out a required element
+	{
+		rec := doPostXML(t, g, "http://deepmap.ai/xml_resource", `<pet><age>3</age></pet>`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send an element the schema doesn't allow
+	{
+		rec := doPostXML(t, g, "http://deepmap.ai/xml_resource", `<pet><name>Rex</name><owner>me</owner></pet>`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+
+	// Send malformed XML
+	{
+		rec := doPostXML(t, g, "http://deepmap.ai/xml_resource", `<pet><name>Rex</pet>`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, called, "Handler should not have been called")
+	}
+}